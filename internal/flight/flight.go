@@ -0,0 +1,164 @@
+// Package flight provides duplicate call suppression for concurrent lookups
+// sharing the same key
+package flight
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Stats reports how many calls a Group has served and how many of them were
+// coalesced into an already in-flight call
+type Stats struct {
+	Calls     uint64 `json:"calls"`
+	Executed  uint64 `json:"executed"`
+	Coalesced uint64 `json:"coalesced"`
+}
+
+// Group coalesces concurrent calls with the same key into a single execution
+// whose result is shared by every caller
+type Group[T any] struct {
+	mu    sync.Mutex
+	calls map[string]*call[T]
+
+	numCalls     uint64
+	numExecuted  uint64
+	numCoalesced uint64
+}
+
+// call is a single in-flight execution and the callers waiting on it
+type call[T any] struct {
+	done      chan struct{}
+	cancel    context.CancelFunc
+	waiters   int
+	deadlines []time.Time // Deadlines of the waiting callers that have one
+	val       T
+	err       error
+}
+
+// join adds a caller waiting on the call
+func (c *call[T]) join(ctx context.Context) {
+	c.waiters++
+	if d, ok := ctx.Deadline(); ok {
+		c.deadlines = append(c.deadlines, d)
+	}
+}
+
+// leave removes a caller that gave up waiting on the call, reporting whether
+// any callers remain
+func (c *call[T]) leave(ctx context.Context) bool {
+	c.waiters--
+	if d, ok := ctx.Deadline(); ok {
+		for i := range c.deadlines {
+			if c.deadlines[i].Equal(d) {
+				c.deadlines = append(c.deadlines[:i], c.deadlines[i+1:]...)
+				break
+			}
+		}
+	}
+	return c.waiters > 0
+}
+
+// deadline returns the earliest deadline of the waiting callers
+func (c *call[T]) deadline() (deadline time.Time, ok bool) {
+	for _, d := range c.deadlines {
+		if !ok || d.Before(deadline) {
+			deadline, ok = d, true
+		}
+	}
+	return deadline, ok
+}
+
+// Do executes fn for the passed key, or waits on an in-flight execution for
+// the same key if there is one. fn runs with a context that carries the
+// values of the first caller, reports the earliest deadline of the callers
+// still waiting and is only cancelled once every waiting caller has given up.
+// shared reports whether the result was given to more than one caller
+func (g *Group[T]) Do(ctx context.Context, key string, fn func(ctx context.Context) (T, error)) (v T, shared bool, err error) {
+	atomic.AddUint64(&g.numCalls, 1)
+
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*call[T])
+	}
+	c, ok := g.calls[key]
+	if ok {
+		c.join(ctx)
+		g.mu.Unlock()
+		atomic.AddUint64(&g.numCoalesced, 1)
+		return g.wait(ctx, key, c, true)
+	}
+
+	// Start a new execution detached from the callers cancellation
+	c = &call[T]{done: make(chan struct{})}
+	c.join(ctx)
+	fctx, cancel := context.WithCancel(detached{parent: ctx, deadline: func() (time.Time, bool) {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		return c.deadline()
+	}})
+	c.cancel = cancel
+	g.calls[key] = c
+	g.mu.Unlock()
+	atomic.AddUint64(&g.numExecuted, 1)
+
+	go func() {
+		defer cancel()
+		c.val, c.err = fn(fctx)
+
+		g.mu.Lock()
+		if g.calls[key] == c {
+			delete(g.calls, key)
+		}
+		g.mu.Unlock()
+		close(c.done)
+	}()
+	return g.wait(ctx, key, c, false)
+}
+
+// wait blocks until the call completes or the callers context is done,
+// cancelling the call if no other callers remain
+func (g *Group[T]) wait(ctx context.Context, key string, c *call[T], joined bool) (v T, shared bool, err error) {
+	select {
+	case <-c.done:
+		g.mu.Lock()
+		shared = joined || c.waiters > 1
+		g.mu.Unlock()
+		return c.val, shared, c.err
+	case <-ctx.Done():
+		g.mu.Lock()
+		if !c.leave(ctx) {
+			c.cancel()
+			// Forget the call so new callers don't join a cancelled execution
+			if g.calls[key] == c {
+				delete(g.calls, key)
+			}
+		}
+		g.mu.Unlock()
+		return v, joined, ctx.Err()
+	}
+}
+
+// Stats returns a snapshot of the groups counters
+func (g *Group[T]) Stats() Stats {
+	return Stats{
+		Calls:     atomic.LoadUint64(&g.numCalls),
+		Executed:  atomic.LoadUint64(&g.numExecuted),
+		Coalesced: atomic.LoadUint64(&g.numCoalesced),
+	}
+}
+
+// detached is a context that carries the values of its parent but never
+// inherits its cancellation. Its deadline is that of the waiting callers, so
+// work can tell how long its result is still wanted
+type detached struct {
+	parent   context.Context
+	deadline func() (time.Time, bool)
+}
+
+func (d detached) Deadline() (time.Time, bool)       { return d.deadline() }
+func (d detached) Done() <-chan struct{}             { return nil }
+func (d detached) Err() error                        { return nil }
+func (d detached) Value(key interface{}) interface{} { return d.parent.Value(key) }
@@ -0,0 +1,200 @@
+package flight
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// waitCalls blocks until the group has seen n calls
+func waitCalls(t *testing.T, g *Group[int], n uint64) {
+	t.Helper()
+	for start := time.Now(); g.Stats().Calls < n; time.Sleep(time.Millisecond) {
+		if time.Since(start) > time.Second {
+			t.Fatalf("Timed out waiting for %d calls, got %d", n, g.Stats().Calls)
+		}
+	}
+}
+
+func TestDoCoalesces(t *testing.T) {
+	var g Group[int]
+	release := make(chan struct{})
+	var executions int
+	fn := func(ctx context.Context) (int, error) {
+		executions++
+		<-release
+		return 42, nil
+	}
+
+	const callers = 5
+	var wg sync.WaitGroup
+	results := make([]int, callers)
+	shared := make([]bool, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], shared[i], _ = g.Do(context.Background(), "key", fn)
+		}(i)
+	}
+	waitCalls(t, &g, callers)
+	close(release)
+	wg.Wait()
+
+	if executions != 1 {
+		t.Errorf("Expected a single execution, got %d", executions)
+	}
+	for i := range results {
+		if results[i] != 42 || !shared[i] {
+			t.Errorf("Caller %d: expected the shared result 42, got %d shared %v", i, results[i], shared[i])
+		}
+	}
+	if s := g.Stats(); s != (Stats{Calls: callers, Executed: 1, Coalesced: callers - 1}) {
+		t.Errorf("Unexpected stats %+v", s)
+	}
+
+	// Completed calls are forgotten and other keys run separately
+	v, shared1, err := g.Do(context.Background(), "key", func(context.Context) (int, error) { return 1, nil })
+	if v != 1 || shared1 || err != nil {
+		t.Errorf("Expected a new unshared execution, got %d %v %v", v, shared1, err)
+	}
+}
+
+func TestDoWaiterCancellation(t *testing.T) {
+	var g Group[int]
+	release := make(chan struct{})
+	fnCtx := make(chan context.Context, 1)
+	fn := func(ctx context.Context) (int, error) {
+		fnCtx <- ctx
+		<-release
+		return 42, ctx.Err()
+	}
+
+	type result struct {
+		v   int
+		err error
+	}
+	first := make(chan result)
+	go func() {
+		v, _, err := g.Do(context.Background(), "key", fn)
+		first <- result{v, err}
+	}()
+	waitCalls(t, &g, 1)
+
+	// A waiter giving up returns at once without cancelling the execution
+	ctx, cancel := context.WithCancel(context.Background())
+	second := make(chan result)
+	go func() {
+		v, _, err := g.Do(ctx, "key", fn)
+		second <- result{v, err}
+	}()
+	waitCalls(t, &g, 2)
+	cancel()
+	if r := <-second; r.err != context.Canceled {
+		t.Errorf("Expected the cancelled waiter to return context.Canceled, got %+v", r)
+	}
+	if err := (<-fnCtx).Err(); err != nil {
+		t.Errorf("Expected the execution to continue for the remaining waiter, got %v", err)
+	}
+	close(release)
+	if r := <-first; r.v != 42 || r.err != nil {
+		t.Errorf("Expected the remaining waiter to get the result, got %+v", r)
+	}
+}
+
+func TestDoLastWaiterCancellation(t *testing.T) {
+	var g Group[int]
+	cancelled := make(chan error, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		_, _, err := g.Do(ctx, "key", func(ctx context.Context) (int, error) {
+			<-ctx.Done()
+			cancelled <- ctx.Err()
+			return 0, ctx.Err()
+		})
+		done <- err
+	}()
+	waitCalls(t, &g, 1)
+	cancel()
+
+	if err := <-done; err != context.Canceled {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	select {
+	case err := <-cancelled:
+		if err != context.Canceled {
+			t.Errorf("Expected the execution to be cancelled, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the execution to be cancelled once its last waiter gave up")
+	}
+
+	// New callers don't join the cancelled execution
+	v, _, err := g.Do(context.Background(), "key", func(context.Context) (int, error) { return 7, nil })
+	if v != 7 || err != nil {
+		t.Errorf("Expected a new execution, got %d %v", v, err)
+	}
+}
+
+func TestDoDeadline(t *testing.T) {
+	var g Group[int]
+	release := make(chan struct{})
+	fnCtx := make(chan context.Context, 1)
+	fn := func(ctx context.Context) (int, error) {
+		fnCtx <- ctx
+		<-release
+		return 0, nil
+	}
+	type key struct{}
+
+	late := time.Now().Add(time.Hour)
+	lateCtx, cancelLate := context.WithDeadline(context.WithValue(context.Background(), key{}, "first"), late)
+	defer cancelLate()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		g.Do(lateCtx, "key", fn)
+	}()
+	ctx := <-fnCtx
+	if d, ok := ctx.Deadline(); !ok || !d.Equal(late) {
+		t.Errorf("Expected the first callers deadline, got %v %v", d, ok)
+	}
+	if v := ctx.Value(key{}); v != "first" {
+		t.Errorf("Expected the first callers values, got %v", v)
+	}
+
+	// A waiter with an earlier deadline shortens it until it gives up
+	early := time.Now().Add(time.Minute)
+	earlyCtx, cancelEarly := context.WithDeadline(context.Background(), early)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		g.Do(earlyCtx, "key", fn)
+	}()
+	waitCalls(t, &g, 2)
+	if d, ok := ctx.Deadline(); !ok || !d.Equal(early) {
+		t.Errorf("Expected the earliest deadline, got %v %v", d, ok)
+	}
+
+	// Waiters without a deadline don't lift it
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		g.Do(context.Background(), "key", fn)
+	}()
+	waitCalls(t, &g, 3)
+	cancelEarly()
+	for start := time.Now(); ; time.Sleep(time.Millisecond) {
+		if d, _ := ctx.Deadline(); d.Equal(late) {
+			break
+		}
+		if time.Since(start) > time.Second {
+			t.Fatal("Expected the deadline to return to the first callers once the earlier waiter left")
+		}
+	}
+	close(release)
+	wg.Wait()
+}
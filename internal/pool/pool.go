@@ -0,0 +1,33 @@
+// Package pool runs independent units of work on a bounded number of
+// goroutines
+package pool
+
+import "sync"
+
+// Each calls f with every index from 0 to n-1 using at most workers
+// goroutines, returning once every call has returned
+func Each(n, workers int, f func(i int)) {
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > n {
+		workers = n
+	}
+
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				f(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+}
@@ -0,0 +1,41 @@
+package pool
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestEach(t *testing.T) {
+	var mu sync.Mutex
+	var active, peak int
+	done := make([]bool, 10)
+	Each(len(done), 3, func(i int) {
+		mu.Lock()
+		if active++; active > peak {
+			peak = active
+		}
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		mu.Lock()
+		active--
+		done[i] = true
+		mu.Unlock()
+	})
+	for i, ok := range done {
+		if !ok {
+			t.Errorf("Expected index %d to be run", i)
+		}
+	}
+	if peak > 3 {
+		t.Errorf("Expected at most 3 concurrent calls, got %d", peak)
+	}
+
+	// Nothing to do and no workers are both fine
+	Each(0, 3, func(int) { t.Error("Expected no calls") })
+	var calls int
+	Each(2, 0, func(int) { calls++ })
+	if calls != 2 {
+		t.Errorf("Expected 2 calls with a single worker, got %d", calls)
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"log"
 	"os"
 
@@ -8,18 +9,22 @@ import (
 )
 
 func main() {
-	// Start a new service
-	service.Start(getenv("PORT", "8080")) // The port the server will run on
-}
+	printConfig := flag.Bool("print-config", false, "print the effective config as YAML and exit")
 
-// getenv attempts to retrieve and return a variable from the environment. If it
-// fails it will either crash or failover to a passed default value
-func getenv(key string, def ...string) string {
-	if v, ok := os.LookupEnv(key); ok {
-		return v
+	// Load the config from flags, the environment and an optional config file
+	cfg, err := service.LoadConfig(flag.CommandLine, os.Args[1:], os.LookupEnv)
+	if err != nil {
+		log.Fatal(err)
 	}
-	if len(def) == 0 {
-		log.Fatalf("%s not defined in environment", key)
+	if *printConfig {
+		b, err := cfg.YAML()
+		if err != nil {
+			log.Fatal(err)
+		}
+		os.Stdout.Write(b)
+		return
 	}
-	return def[0]
+
+	// Start a new service
+	service.StartWithConfig(cfg)
 }
@@ -0,0 +1,48 @@
+package ovrstat
+
+import (
+	"context"
+
+	"github.com/s32x/ovrstat/internal/pool"
+)
+
+// DefaultBatchWorkers is the number of lookups a batch performs concurrently
+// unless configured with WithBatchWorkers
+const DefaultBatchWorkers = 4
+
+// BatchLookup identifies a player to look up in a batch
+type BatchLookup struct {
+	Platform string `json:"platform"`
+	Tag      string `json:"tag"`
+}
+
+// BatchResult is the outcome of a single lookup in a batch, holding either
+// the players stats or the error their lookup failed with
+type BatchResult struct {
+	BatchLookup
+	Stats *PlayerStats
+	Err   error
+}
+
+// WithBatchWorkers sets how many lookups BatchStats performs concurrently
+func WithBatchWorkers(n int) Option {
+	return func(c *Client) { c.batchWorkers = n }
+}
+
+// BatchStats retrieves the stats of several players using the DefaultClient
+func BatchStats(ctx context.Context, players []BatchLookup) []BatchResult {
+	return DefaultClient.BatchStats(ctx, players)
+}
+
+// BatchStats retrieves the stats of several players concurrently, returning a
+// result for every player in the order passed. A failed lookup only fails its
+// own result, the remaining players are still looked up
+func (c *Client) BatchStats(ctx context.Context, players []BatchLookup) []BatchResult {
+	results := make([]BatchResult, len(players))
+	pool.Each(len(players), c.batchWorkers, func(i int) {
+		p := players[i]
+		stats, err := c.StatsContext(ctx, p.Platform, p.Tag)
+		results[i] = BatchResult{BatchLookup: p, Stats: stats, Err: err}
+	})
+	return results
+}
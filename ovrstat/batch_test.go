@@ -0,0 +1,70 @@
+package ovrstat
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestBatchStats(t *testing.T) {
+	var mu sync.Mutex
+	var active, peak int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/search/") {
+			http.ServeFile(w, r, filepath.Join("testdata", "search", "viz.json"))
+			return
+		}
+		mu.Lock()
+		if active++; active > peak {
+			peak = active
+		}
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		active--
+		mu.Unlock()
+		if r.URL.Path != "/career/pc/Viz-1213" {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeFile(w, r, filepath.Join("testdata", "career", "pc-public.html"))
+	}))
+	defer srv.Close()
+
+	c := NewClient(WithBaseURL(srv.URL+"/career"), WithAPIURL(srv.URL+"/search/"),
+		WithBatchWorkers(2), WithCoalescing(false))
+	players := []BatchLookup{
+		{PlatformPC, "Viz-1213"},
+		{PlatformPC, "Nobody-0000"},
+		{"wii", "Mario"},
+		{PlatformPC, "Viz#1213"},
+		{PlatformPC, "Viz"},
+		{PlatformPC, "Someone-1111"},
+	}
+	results := c.BatchStats(context.Background(), players)
+	if len(results) != len(players) {
+		t.Fatalf("Expected %d results, got %d", len(players), len(results))
+	}
+	for i, want := range []error{nil, ErrPlayerNotFound, ErrInvalidPlatform, nil, ErrInvalidTag, ErrPlayerNotFound} {
+		r := results[i]
+		if r.BatchLookup != players[i] {
+			t.Errorf("Result %d is for %+v, want %+v", i, r.BatchLookup, players[i])
+		}
+		if want == nil && (r.Err != nil || r.Stats == nil || r.Stats.Name != "Viz#1213") {
+			t.Errorf("Result %d: expected the stats of Viz#1213, got %+v", i, r)
+		}
+		if want != nil && (!errors.Is(r.Err, want) || r.Stats != nil) {
+			t.Errorf("Result %d: expected %v, got %+v", i, want, r)
+		}
+	}
+	if peak > 2 {
+		t.Errorf("Expected at most 2 concurrent career requests, got %d", peak)
+	}
+}
@@ -0,0 +1,158 @@
+package ovrstat
+
+import (
+	"sync"
+	"time"
+)
+
+// BreakerState is the state of a CircuitBreaker
+type BreakerState int
+
+const (
+	// BreakerClosed lets all requests through
+	BreakerClosed BreakerState = iota
+
+	// BreakerOpen fails all requests fast until its cooldown has passed
+	BreakerOpen
+
+	// BreakerHalfOpen lets a single probe request through, closing the
+	// breaker if it succeeds and opening it again if it fails
+	BreakerHalfOpen
+)
+
+// String returns the name of the state
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// MarshalText encodes the state as its name
+func (s BreakerState) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// BreakerStatus is a snapshot of a CircuitBreakers state
+type BreakerStatus struct {
+	State               BreakerState `json:"state"`
+	ConsecutiveFailures int          `json:"consecutiveFailures"`
+	OpenedAt            *time.Time   `json:"openedAt,omitempty"`
+	RetryAt             *time.Time   `json:"retryAt,omitempty"`
+}
+
+// CircuitBreaker stops upstream requests after a run of consecutive failures,
+// failing fast with ErrCircuitOpen until a probe request succeeds after the
+// cooldown
+type CircuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	state     BreakerState
+	failures  int
+	openedAt  time.Time
+	probing   bool
+}
+
+// NewCircuitBreaker creates a CircuitBreaker that opens after threshold
+// consecutive failures and probes upstream again after cooldown
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{threshold: threshold, cooldown: cooldown}
+}
+
+// WithCircuitBreaker sets the CircuitBreaker guarding all upstream requests.
+// A breaker may be shared between clients talking to the same upstream
+func WithCircuitBreaker(b *CircuitBreaker) Option {
+	return func(c *Client) { c.breaker = b }
+}
+
+// allow reports whether a request may be made, returning ErrCircuitOpen if
+// not. probe is true if the request is the half-open breakers probe and must
+// be passed back to record or release
+func (b *CircuitBreaker) allow() (probe bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case BreakerOpen:
+		if time.Since(b.openedAt) < b.cooldown {
+			return false, ErrCircuitOpen
+		}
+		b.state = BreakerHalfOpen
+	case BreakerHalfOpen:
+		if b.probing {
+			return false, ErrCircuitOpen
+		}
+	default:
+		return false, nil
+	}
+	b.probing = true
+	return true, nil
+}
+
+// record records the outcome of an allowed request. Only the probe decides
+// the outcome of a half-open breaker, requests allowed before the breaker
+// opened that complete late are ignored
+func (b *CircuitBreaker) record(probe, success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if probe {
+		b.probing = false
+	} else if b.state != BreakerClosed {
+		return
+	}
+	if success {
+		b.state = BreakerClosed
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.state == BreakerHalfOpen || b.failures >= b.threshold {
+		b.state = BreakerOpen
+		b.openedAt = time.Now()
+	}
+}
+
+// release releases an allowed request whose outcome is unknown, letting a
+// new probe through if it was one
+func (b *CircuitBreaker) release(probe bool) {
+	if !probe {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+// State returns the current state of the breaker
+func (b *CircuitBreaker) State() BreakerState {
+	return b.Status().State
+}
+
+// Status returns a snapshot of the breakers state
+func (b *CircuitBreaker) Status() BreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s := BreakerStatus{State: b.state, ConsecutiveFailures: b.failures}
+	if b.state != BreakerClosed {
+		openedAt, retryAt := b.openedAt, b.openedAt.Add(b.cooldown)
+		s.OpenedAt, s.RetryAt = &openedAt, &retryAt
+	}
+	return s
+}
+
+// RetryIn returns how long until the breaker lets a probe request through
+func (b *CircuitBreaker) RetryIn() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state != BreakerOpen {
+		return 0
+	}
+	if d := b.cooldown - time.Since(b.openedAt); d > 0 {
+		return d
+	}
+	return 0
+}
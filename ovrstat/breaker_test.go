@@ -0,0 +1,112 @@
+package ovrstat
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/time/rate"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	var hits int32
+	var healthy atomic.Value
+	healthy.Store(false)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		if !healthy.Load().(bool) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	b := NewCircuitBreaker(2, 50*time.Millisecond)
+	c := NewClient(WithBaseURL(srv.URL), WithRetryPolicy(NoRetry), WithCircuitBreaker(b))
+
+	// Consecutive failures open the breaker
+	for i := 0; i < 2; i++ {
+		if _, err := c.PCStats("Viz-1213"); errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("Breaker opened after %d failures", i)
+		}
+	}
+	if b.State() != BreakerOpen {
+		t.Fatalf("Expected breaker to be open, got %v", b.State())
+	}
+
+	// Open breakers fail fast without contacting upstream
+	if _, err := c.PCStats("Viz-1213"); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected ErrCircuitOpen, got %v", err)
+	}
+	if hits != 2 {
+		t.Errorf("Expected 2 upstream requests, got %d", hits)
+	}
+
+	// A successful probe after the cooldown closes the breaker
+	healthy.Store(true)
+	time.Sleep(60 * time.Millisecond)
+	if _, err := c.PCStats("Viz-1213"); err != ErrPlayerNotFound {
+		t.Errorf("Expected ErrPlayerNotFound from probe, got %v", err)
+	}
+	if b.State() != BreakerClosed {
+		t.Errorf("Expected breaker to be closed, got %v", b.State())
+	}
+}
+
+func TestCircuitBreakerProbe(t *testing.T) {
+	b := NewCircuitBreaker(1, 10*time.Millisecond)
+	late, _ := b.allow()
+	if late {
+		t.Fatal("Expected a closed breaker not to allow a probe")
+	}
+	b.record(false, false)
+	time.Sleep(20 * time.Millisecond)
+	probe, err := b.allow()
+	if !probe || err != nil {
+		t.Fatalf("Expected a probe after the cooldown, got %v %v", probe, err)
+	}
+
+	// Requests allowed before the breaker opened don't end the probe
+	b.record(late, true)
+	if b.State() != BreakerHalfOpen {
+		t.Fatalf("Expected a late request to leave the breaker half-open, got %v", b.State())
+	}
+	if _, err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected a second probe to be refused, got %v", err)
+	}
+	b.release(late)
+	if _, err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected a released late request to leave the probe running, got %v", err)
+	}
+
+	b.record(probe, false)
+	if b.State() != BreakerOpen {
+		t.Errorf("Expected a failed probe to open the breaker, got %v", b.State())
+	}
+}
+
+func TestCircuitBreakerRateLimit(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	b := NewCircuitBreaker(1, time.Minute)
+	l := rate.NewLimiter(rate.Every(time.Hour), 2)
+	c := NewClient(WithBaseURL(srv.URL), WithRetryPolicy(NoRetry), WithCircuitBreaker(b), WithRateLimiter(l))
+	c.PCStats("Viz-1213")
+
+	// Open breakers fail fast without spending rate limit tokens
+	for i := 0; i < 3; i++ {
+		if _, err := c.PCStats("Viz-1213"); !errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("Expected ErrCircuitOpen, got %v", err)
+		}
+	}
+	if !l.Allow() {
+		t.Error("Expected a rate limit token to remain")
+	}
+}
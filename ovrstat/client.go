@@ -0,0 +1,316 @@
+package ovrstat
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/s32x/ovrstat/internal/flight"
+	"golang.org/x/time/rate"
+)
+
+// DefaultClient is the Client used by the package level Stats, PCStats and
+// ConsoleStats functions
+var DefaultClient = NewClient()
+
+// Client retrieves Overwatch stats using a configurable http.Client, upstream
+// URLs and request headers
+type Client struct {
+	httpClient *http.Client
+	baseURL    string
+	apiURL     string
+	header     http.Header
+	retry      RetryPolicy
+	limiter    *rate.Limiter
+	breaker    *CircuitBreaker
+	hooks      []Hooks
+	logger     *slog.Logger
+
+	legacyStats   bool
+	rejectPrivate bool
+	resolution    Resolution
+
+	caseInsensitive bool
+	batchWorkers    int
+
+	coalesce bool
+	flight   flight.Group[*PlayerStats]
+}
+
+// CoalesceStats reports how many lookups a Client has served and how many of
+// them shared an upstream fetch already in flight for the same player
+type CoalesceStats = flight.Stats
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithHTTPClient sets the http.Client used for all upstream requests
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.httpClient = hc }
+}
+
+// WithBaseURL sets the base URL that career profiles are scraped from
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) { c.baseURL = baseURL }
+}
+
+// WithAPIURL sets the URL of the account-by-name search API
+func WithAPIURL(apiURL string) Option {
+	return func(c *Client) { c.apiURL = apiURL }
+}
+
+// WithHeader adds a header that is sent on every upstream request
+func WithHeader(key, value string) Option {
+	return func(c *Client) { c.header.Add(key, value) }
+}
+
+// WithLegacyCareerStats populates the untyped CareerStats category maps
+// alongside the typed Stats and encodes CareerStats in its original JSON
+// shape, holding only the category maps
+func WithLegacyCareerStats() Option {
+	return func(c *Client) { c.legacyStats = true }
+}
+
+// WithRejectPrivate returns ErrPrivateProfile for private profiles instead of
+// stats with Private set
+func WithRejectPrivate() Option {
+	return func(c *Client) { c.rejectPrivate = true }
+}
+
+// WithCoalescing enables or disables sharing a single upstream fetch between
+// concurrent lookups of the same player. Coalescing is enabled by default, in
+// which case coalesced callers receive the same *PlayerStats and must not
+// modify it
+func WithCoalescing(enabled bool) Option {
+	return func(c *Client) { c.coalesce = enabled }
+}
+
+// WithCaseInsensitiveTags looks players up ignoring the case of their tag.
+// When a career page isn't found the search API is queried for a tag
+// differing only in case and the lookup retried with it, costing an extra
+// upstream request. PlayerStats.Tag holds the canonical tag
+func WithCaseInsensitiveTags() Option {
+	return func(c *Client) { c.caseInsensitive = true }
+}
+
+// WithRateLimiter sets a token bucket limiting the rate of upstream requests,
+// including retries. Requests wait for a token unless the wait would exceed
+// the callers deadline, in which case ErrThrottled is returned. Coalesced
+// lookups use the earliest deadline of the callers waiting on them
+func WithRateLimiter(l *rate.Limiter) Option {
+	return func(c *Client) { c.limiter = l }
+}
+
+// NewClient creates and returns a new Client configured with the passed
+// options
+func NewClient(opts ...Option) *Client {
+	c := &Client{
+		httpClient:   http.DefaultClient,
+		baseURL:      baseURL,
+		apiURL:       apiURL,
+		header:       make(http.Header),
+		retry:        DefaultRetryPolicy,
+		resolution:   ResolveExact,
+		batchWorkers: DefaultBatchWorkers,
+		coalesce:     true,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Stats retrieves player stats
+// Universal method if you don't need to differentiate it
+func (c *Client) Stats(platform, tag string) (*PlayerStats, error) {
+	return c.StatsContext(context.Background(), platform, tag)
+}
+
+// StatsContext retrieves player stats, aborting all upstream requests once the
+// passed context is cancelled
+func (c *Client) StatsContext(ctx context.Context, platform, tag string) (*PlayerStats, error) {
+	switch platform {
+	case PlatformPC:
+		return c.PCStatsContext(ctx, tag) // Perform a stats lookup for PC
+	case PlatformPSN, PlatformXBL, PlatformNS:
+		return c.ConsoleStatsContext(ctx, platform, tag) // Perform a stats lookup for Console
+	default:
+		return nil, ErrInvalidPlatform
+	}
+}
+
+// ConsoleStats retrieves player stats for Console
+func (c *Client) ConsoleStats(platform, tag string) (*PlayerStats, error) {
+	return c.ConsoleStatsContext(context.Background(), platform, tag)
+}
+
+// ConsoleStatsContext retrieves player stats for Console using the passed
+// context
+func (c *Client) ConsoleStatsContext(ctx context.Context, platform, tag string) (*PlayerStats, error) {
+	return c.StatsByID(ctx, platform, tag, 0)
+}
+
+// PCStats retrieves player stats for PC
+func (c *Client) PCStats(tag string) (*PlayerStats, error) {
+	return c.PCStatsContext(context.Background(), tag)
+}
+
+// PCStatsContext retrieves player stats for PC using the passed context
+func (c *Client) PCStatsContext(ctx context.Context, tag string) (*PlayerStats, error) {
+	return c.StatsByID(ctx, PlatformPC, tag, 0)
+}
+
+// StatsByID retrieves the stats of the account with the passed ID among the
+// accounts matching the platform and tag, e.g. one of the candidates of an
+// AmbiguousError. ErrPlayerNotFound is returned if no matching account has
+// the ID, and a zero ID resolves the account as StatsContext does
+func (c *Client) StatsByID(ctx context.Context, platform, tag string, id int) (*PlayerStats, error) {
+	pid, err := ParsePlayerID(platform, tag)
+	if err != nil {
+		return nil, err
+	}
+	return c.lookup(ctx, pid, id)
+}
+
+// CoalesceStats returns a snapshot of the clients lookup coalescing counters
+func (c *Client) CoalesceStats() CoalesceStats {
+	return c.flight.Stats()
+}
+
+// lookup performs a stats lookup, joining any lookup already in flight for the
+// same player and account ID
+func (c *Client) lookup(ctx context.Context, pid PlayerID, id int) (*PlayerStats, error) {
+	if !c.coalesce {
+		return c.fetch(ctx, pid, id)
+	}
+	key := pid.careerPath()
+	if id != 0 {
+		key += "#" + strconv.Itoa(id)
+	}
+	ps, _, err := c.flight.Do(ctx, key, func(ctx context.Context) (*PlayerStats, error) {
+		return c.fetch(ctx, pid, id)
+	})
+	if err != nil && err == ctx.Err() {
+		// The caller gave up while waiting on the shared upstream fetch
+		return nil, &UpstreamError{URL: c.baseURL + pid.careerPath(), Err: err}
+	}
+	return ps, err
+}
+
+// fetch retrieves stats from upstream, reporting the lookup to the clients
+// hooks
+func (c *Client) fetch(ctx context.Context, pid PlayerID, id int) (*PlayerStats, error) {
+	c.lookupStarted(ctx, pid, id)
+	start := time.Now()
+	ps, err := c.playerStats(ctx, pid, id)
+	c.lookedUp(ctx, LookupEvent{Player: pid, ID: id, Stats: ps, Duration: time.Since(start), Err: err})
+	return ps, err
+}
+
+// get performs a GET request against the passed url, retrying it according
+// to the clients RetryPolicy. Failed requests and non-200 responses are
+// returned as an UpstreamError
+func (c *Client) get(ctx context.Context, endpoint, url string) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		res, err := c.do(ctx, endpoint, url, attempt)
+		if err == nil {
+			return res, nil
+		}
+		ue, ok := err.(*UpstreamError)
+		if !ok {
+			return nil, err
+		}
+		ue.Attempts = attempt
+
+		// Stop retrying once the caller has given up
+		delay, retry := c.retry.delay(attempt, ue)
+		if !retry || ctx.Err() != nil {
+			return nil, ue
+		}
+		c.logRetry(ctx, endpoint, attempt, delay, ue)
+		t := time.NewTimer(delay)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return nil, ue
+		}
+	}
+}
+
+// do performs a single GET request against the passed url, applying all of
+// the clients configured headers, outbound rate limit and circuit breaker and
+// reporting the attempt to the clients hooks
+func (c *Client) do(ctx context.Context, endpoint, url string, attempt int) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range c.header {
+		req.Header[k] = v
+	}
+	if id := RequestID(ctx); id != "" {
+		req.Header.Set(RequestIDHeader, id)
+	}
+
+	// The breaker is checked first so an open breaker fails fast without
+	// spending rate limit tokens
+	var probe bool
+	if c.breaker != nil {
+		if probe, err = c.breaker.allow(); err != nil {
+			return nil, err
+		}
+	}
+	if c.limiter != nil {
+		if err := c.limiter.Wait(ctx); err != nil {
+			if c.breaker != nil {
+				c.breaker.release(probe)
+			}
+			return nil, errors.Wrap(ErrThrottled, err.Error())
+		}
+	}
+
+	ev := UpstreamEvent{Endpoint: endpoint, URL: url, Attempt: attempt}
+	start := time.Now()
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		ev.Err, ev.Duration = err, time.Since(start)
+		c.upstream(ctx, ev)
+
+		// Requests abandoned by the caller say nothing about upstream health
+		if c.breaker != nil {
+			if ctx.Err() != nil {
+				c.breaker.release(probe)
+			} else {
+				c.breaker.record(probe, false)
+			}
+		}
+		return nil, &UpstreamError{URL: url, Err: err}
+	}
+	if c.breaker != nil {
+		c.breaker.record(probe, !isUpstreamFailure(res.StatusCode))
+	}
+	ev.StatusCode = res.StatusCode
+	res.Body = &observedBody{ReadCloser: res.Body, onClose: func(bytes int64) {
+		ev.Bytes, ev.Duration = bytes, time.Since(start)
+		c.upstream(ctx, ev)
+	}}
+	if res.StatusCode != http.StatusOK {
+		res.Body.Close()
+		return nil, &UpstreamError{
+			URL:        url,
+			StatusCode: res.StatusCode,
+			RetryAfter: parseRetryAfter(res.Header.Get("Retry-After"), time.Now()),
+		}
+	}
+	return res, nil
+}
+
+// isUpstreamFailure reports whether a response status indicates upstream is
+// unhealthy or refusing our requests
+func isUpstreamFailure(status int) bool {
+	return status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+}
@@ -0,0 +1,110 @@
+package ovrstat
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/time/rate"
+)
+
+// notFoundPage is the career page served for players that don't exist
+const notFoundPage = `<html><body><h1 class="u-align-center">Profile Not Found</h1></body></html>`
+
+// countingTransport is an http.RoundTripper counting the requests it sends
+type countingTransport struct {
+	requests atomic.Int32
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.requests.Add(1)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestClientOptions(t *testing.T) {
+	var headers atomic.Value
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers.Store(r.Header.Clone())
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(notFoundPage))
+	}))
+	defer srv.Close()
+
+	transport := &countingTransport{}
+	c := NewClient(
+		WithBaseURL(srv.URL),
+		WithAPIURL(srv.URL+"/search/"),
+		WithHTTPClient(&http.Client{Transport: transport}),
+		WithHeader("User-Agent", "ovrstat-test"),
+		WithHeader("X-Forwarded-For", "10.0.0.1"),
+		WithHeader("X-Forwarded-For", "10.0.0.2"),
+	)
+	c.PCStats("Viz-1213")
+
+	if transport.requests.Load() == 0 {
+		t.Fatal("Expected requests to be sent through the configured http.Client")
+	}
+	h, _ := headers.Load().(http.Header)
+	if ua := h.Get("User-Agent"); ua != "ovrstat-test" {
+		t.Errorf("Expected the configured User-Agent, got %q", ua)
+	}
+	if xff := h.Values("X-Forwarded-For"); len(xff) != 2 || xff[0] != "10.0.0.1" || xff[1] != "10.0.0.2" {
+		t.Errorf("Expected every added header value to be sent, got %q", xff)
+	}
+}
+
+func TestStatsContext(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer srv.Close()
+	c := NewClient(WithBaseURL(srv.URL), WithAPIURL(srv.URL+"/search/"))
+
+	// Cancelled contexts abort before anything is requested
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.StatsContext(ctx, PlatformPC, "Viz-1213"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if n := requests.Load(); n != 0 {
+		t.Errorf("Expected no upstream requests, got %d", n)
+	}
+
+	// Expiring contexts abort requests in flight
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := c.StatsContext(ctx, PlatformPC, "Viz-1213"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("Expected the lookup to abort at its deadline, took %v", d)
+	}
+}
+
+func TestRateLimiterDeadline(t *testing.T) {
+	// An exhausted limiter that won't refill before the callers deadline
+	l := rate.NewLimiter(rate.Every(time.Hour), 1)
+	l.Allow()
+
+	for _, coalesce := range []bool{true, false} {
+		c := fixtureClient(t, "pc-public.html", "viz.json")
+		c = NewClient(WithBaseURL(c.baseURL), WithAPIURL(c.apiURL), WithRateLimiter(l), WithCoalescing(coalesce))
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		start := time.Now()
+		_, err := c.StatsContext(ctx, PlatformPC, "Viz-1213")
+		cancel()
+		if !errors.Is(err, ErrThrottled) || time.Since(start) > 500*time.Millisecond {
+			t.Errorf("Coalescing %v: expected ErrThrottled at once, got %v after %v", coalesce, err, time.Since(start))
+		}
+	}
+}
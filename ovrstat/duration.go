@@ -0,0 +1,144 @@
+package ovrstat
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// durationUnits maps every unit name emitted by the career page to its length
+var durationUnits = map[string]time.Duration{
+	"s":       time.Second,
+	"sec":     time.Second,
+	"secs":    time.Second,
+	"second":  time.Second,
+	"seconds": time.Second,
+	"m":       time.Minute,
+	"min":     time.Minute,
+	"mins":    time.Minute,
+	"minute":  time.Minute,
+	"minutes": time.Minute,
+	"h":       time.Hour,
+	"hr":      time.Hour,
+	"hrs":     time.Hour,
+	"hour":    time.Hour,
+	"hours":   time.Hour,
+	"d":       24 * time.Hour,
+	"day":     24 * time.Hour,
+	"days":    24 * time.Hour,
+}
+
+// ParseDuration parses a time value as displayed on the career page. It
+// handles clock formats ("12:34:56", "34:56", "1:02:03:04"), unit formats
+// ("45 minutes", "1 hour", "2.5 hours", "1h 30m", "1 hour, 30 minutes") and
+// the placeholder "--", which parses as zero
+func ParseDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(strings.Replace(s, ",", " ", -1))
+	if s == "" || s == "--" {
+		return 0, nil
+	}
+	if strings.Contains(s, ":") {
+		return parseClockDuration(s)
+	}
+	return parseUnitDuration(s)
+}
+
+// parseClockDuration parses colon separated durations, the last part being
+// seconds and each preceding part the next larger unit up to days
+func parseClockDuration(s string) (time.Duration, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) > 4 {
+		return 0, errors.Errorf("Invalid duration %q", s)
+	}
+	scales := []time.Duration{time.Second, time.Minute, time.Hour, 24 * time.Hour}
+
+	var d time.Duration
+	for i := range parts {
+		n, err := strconv.Atoi(strings.TrimSpace(parts[len(parts)-1-i]))
+		if err != nil || n < 0 {
+			return 0, errors.Errorf("Invalid duration %q", s)
+		}
+		d += time.Duration(n) * scales[i]
+	}
+	return d, nil
+}
+
+// parseUnitDuration parses durations made of one or more number and unit
+// pairs, with or without a space between the number and unit. A bare number
+// is treated as seconds
+func parseUnitDuration(s string) (time.Duration, error) {
+	var d time.Duration
+	for rest := s; rest != ""; rest = strings.TrimSpace(rest) {
+		// Read the numeric value
+		i := strings.IndexFunc(rest, func(r rune) bool {
+			return (r < '0' || r > '9') && r != '.'
+		})
+		if i == 0 {
+			return 0, errors.Errorf("Invalid duration %q", s)
+		}
+		if i < 0 {
+			i = len(rest)
+		}
+		n, err := strconv.ParseFloat(rest[:i], 64)
+		if err != nil {
+			return 0, errors.Errorf("Invalid duration %q", s)
+		}
+		rest = strings.TrimSpace(rest[i:])
+
+		// Read the unit, defaulting to seconds
+		unit := time.Second
+		if rest != "" {
+			j := strings.IndexAny(rest, " 0123456789")
+			if j < 0 {
+				j = len(rest)
+			}
+			u, ok := durationUnits[strings.ToLower(rest[:j])]
+			if !ok {
+				return 0, errors.Errorf("Invalid duration unit in %q", s)
+			}
+			unit = u
+			rest = rest[j:]
+		}
+		d += time.Duration(n * float64(unit))
+	}
+	return d, nil
+}
+
+// isDuration reports whether a raw stat value looks like a time value
+func isDuration(raw string) bool {
+	if strings.Contains(raw, ":") {
+		return true
+	}
+	fields := strings.Fields(raw)
+	if len(fields) < 2 {
+		return false
+	}
+	_, ok := durationUnits[strings.ToLower(strings.Trim(fields[1], ","))]
+	return ok
+}
+
+// Duration is a time.Duration that is encoded in JSON as a number of seconds
+type Duration time.Duration
+
+// Seconds returns the duration as a floating point number of seconds
+func (d Duration) Seconds() float64 {
+	return time.Duration(d).Seconds()
+}
+
+// MarshalJSON encodes the duration as a number of seconds
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.Seconds())
+}
+
+// UnmarshalJSON decodes the duration from a number of seconds
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var secs float64
+	if err := json.Unmarshal(b, &secs); err != nil {
+		return err
+	}
+	*d = Duration(secs * float64(time.Second))
+	return nil
+}
@@ -0,0 +1,87 @@
+package ovrstat
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Duration
+		err  bool
+	}{
+		{"--", 0, false},
+		{"", 0, false},
+		{"34:56", 34*time.Minute + 56*time.Second, false},
+		{"12:34:56", 12*time.Hour + 34*time.Minute + 56*time.Second, false},
+		{"123:45:06", 123*time.Hour + 45*time.Minute + 6*time.Second, false},
+		{"1:02:03:04", 26*time.Hour + 3*time.Minute + 4*time.Second, false},
+		{"45 minutes", 45 * time.Minute, false},
+		{"1 hour", time.Hour, false},
+		{"2.5 hours", 150 * time.Minute, false},
+		{"30 SECONDS", 30 * time.Second, false},
+		{"1h 30m", 90 * time.Minute, false},
+		{"1 hour, 30 minutes", 90 * time.Minute, false},
+		{"5", 5 * time.Second, false},
+		{"1:xx", 0, true},
+		{"1 fortnight", 0, true},
+		{"minutes", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseDuration(tt.in)
+		if (err != nil) != tt.err {
+			t.Errorf("ParseDuration(%q) error = %v, want error %v", tt.in, err, tt.err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseDuration(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestIsDuration(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want bool
+	}{
+		{"12:34:56", true},
+		{"34:56", true},
+		{"45 minutes", true},
+		{"30 SECONDS", true},
+		{"1 hour, 30 minutes", true},
+		{"5", false},
+		{"1,234", false},
+		{"5 eliminations", false},
+		{"--", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := isDuration(tt.raw); got != tt.want {
+			t.Errorf("isDuration(%q) = %v, want %v", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestNewStatSeconds(t *testing.T) {
+	tests := []struct {
+		category, key, raw string
+		unit               Unit
+		value              float64
+	}{
+		// Bare numbers are seconds for time stats and counts otherwise
+		{CategoryCombat, "objectiveTime", "5", UnitSeconds, 5},
+		{CategoryCombat, "unknownStat", "5", UnitCount, 5},
+		{CategoryCombat, "objectiveTime", "30 SECONDS", UnitSeconds, 30},
+		{CategoryCombat, "objectiveTime", "--", UnitSeconds, 0},
+		{CategoryCombat, "objectiveTime", "soon", UnitText, 0},
+		{CategoryGame, "unknownTime", "1 hour, 30 minutes", UnitSeconds, 5400},
+		{CategoryGame, "unknownTime", "34:56", UnitSeconds, 2096},
+	}
+	for _, tt := range tests {
+		s := newStat(tt.category, tt.key, tt.raw)
+		if s.Unit != tt.unit || s.Value != tt.value || s.Raw != tt.raw {
+			t.Errorf("newStat(%q, %q, %q) = %+v, want %v %v", tt.category, tt.key, tt.raw, s, tt.value, tt.unit)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package ovrstat
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrPlayerNotFound is thrown when a player doesn't exist
+	ErrPlayerNotFound = errors.New("Player not found")
+
+	// ErrInvalidPlatform is thrown when the passed params are incorrect
+	ErrInvalidPlatform = errors.New("Invalid platform")
+
+	// ErrInvalidTag is matched by a TagError, thrown when a tag isn't valid on
+	// its platform
+	ErrInvalidTag = errors.New("Invalid tag")
+
+	// ErrInvalidSearch is thrown when searching for an empty name
+	ErrInvalidSearch = errors.New("Invalid search")
+
+	// ErrPrivateProfile is thrown when a players profile is private and the
+	// client was created with WithRejectPrivate
+	ErrPrivateProfile = errors.New("Private profile")
+
+	// ErrAmbiguousPlayer is thrown when more than one account matches a
+	// lookup and the client is unable to choose between them
+	ErrAmbiguousPlayer = errors.New("Ambiguous player")
+
+	// ErrUpstreamTimeout is matched by an UpstreamError caused by a timeout
+	ErrUpstreamTimeout = errors.New("Upstream request timed out")
+
+	// ErrUpstreamStatus is matched by an UpstreamError caused by a non-200
+	// response
+	ErrUpstreamStatus = errors.New("Unexpected upstream response status")
+
+	// ErrRateLimited is matched by an UpstreamError caused by the upstream
+	// rate limiting our requests
+	ErrRateLimited = errors.New("Rate limited by upstream")
+
+	// ErrCircuitOpen is thrown without contacting upstream while the clients
+	// CircuitBreaker is open after repeated upstream failures
+	ErrCircuitOpen = errors.New("Upstream circuit breaker is open")
+
+	// ErrThrottled is thrown when a request can't be made within the callers
+	// deadline due to the clients outbound rate limit
+	ErrThrottled = errors.New("Outbound request rate exceeded")
+
+	// ErrLayoutChanged is matched by a ParseError, meaning an upstream
+	// response no longer has the structure the parser expects
+	ErrLayoutChanged = errors.New("Upstream layout changed")
+)
+
+// UpstreamError is returned when a request to the career site or the
+// account-by-name API fails. Depending on its cause it matches
+// ErrUpstreamTimeout, ErrUpstreamStatus or ErrRateLimited using errors.Is
+type UpstreamError struct {
+	URL        string
+	StatusCode int           // The response status, zero if no response was received
+	RetryAfter time.Duration // The delay requested by upstream, if any
+	Attempts   int           // The number of attempts made
+	Err        error         // The transport error, nil if a response was received
+}
+
+// Error returns a description of the failed request
+func (e *UpstreamError) Error() string {
+	var msg string
+	if e.StatusCode != 0 {
+		msg = fmt.Sprintf("Upstream request to %s returned %d %s",
+			e.URL, e.StatusCode, http.StatusText(e.StatusCode))
+	} else {
+		msg = fmt.Sprintf("Upstream request to %s failed: %v", e.URL, e.Err)
+	}
+	if e.Attempts > 1 {
+		msg += fmt.Sprintf(" (after %d attempts)", e.Attempts)
+	}
+	return msg
+}
+
+// Unwrap returns the transport error that caused the request to fail
+func (e *UpstreamError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether the error matches one of the upstream sentinel errors
+func (e *UpstreamError) Is(target error) bool {
+	switch target {
+	case ErrUpstreamTimeout:
+		var ne net.Error
+		return errors.Is(e.Err, context.DeadlineExceeded) ||
+			(errors.As(e.Err, &ne) && ne.Timeout())
+	case ErrUpstreamStatus:
+		return e.StatusCode != 0
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	}
+	return false
+}
+
+// TagError is returned when a tag isn't valid on its platform, before any
+// upstream request is made. It matches ErrInvalidTag using errors.Is
+type TagError struct {
+	Platform string
+	Tag      string
+	Reason   string // Why the tag is invalid
+}
+
+// Error returns a description of the invalid tag
+func (e *TagError) Error() string {
+	return fmt.Sprintf("Invalid %s tag %q: %s", e.Platform, e.Tag, e.Reason)
+}
+
+// Is reports whether target is ErrInvalidTag
+func (e *TagError) Is(target error) bool {
+	return target == ErrInvalidTag
+}
+
+// ParseError is returned when an upstream response can't be parsed or is
+// missing elements the parser requires. It matches ErrLayoutChanged using
+// errors.Is
+type ParseError struct {
+	Source  string   // The response that failed to parse
+	Missing []string // Selectors of required elements that weren't found
+	Err     error
+}
+
+// Error returns a description of the parse failure
+func (e *ParseError) Error() string {
+	if len(e.Missing) > 0 {
+		return fmt.Sprintf("Failed to parse %s, layout changed: required elements not found: %s",
+			e.Source, strings.Join(e.Missing, ", "))
+	}
+	return fmt.Sprintf("Failed to parse %s: %v", e.Source, e.Err)
+}
+
+// Unwrap returns the underlying parse error
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is ErrLayoutChanged
+func (e *ParseError) Is(target error) bool {
+	return target == ErrLayoutChanged
+}
@@ -0,0 +1,134 @@
+package ovrstat
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// timeoutError is a net.Error reporting a timeout
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestErrorMatching(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int  // StatusCode of the UpstreamError in the chain, -1 if none
+		parse  bool // Whether a ParseError is in the chain
+		tag    bool // Whether a TagError is in the chain
+		is     []error
+		isNot  []error
+	}{
+		{"rate limited", errors.Wrap(&UpstreamError{StatusCode: http.StatusTooManyRequests}, "Failed to retrieve profile"), http.StatusTooManyRequests, false, false,
+			[]error{ErrUpstreamStatus, ErrRateLimited}, []error{ErrUpstreamTimeout, ErrLayoutChanged}},
+		{"unavailable", errors.Wrap(errors.Wrap(&UpstreamError{StatusCode: http.StatusServiceUnavailable}, "inner"), "outer"), http.StatusServiceUnavailable, false, false,
+			[]error{ErrUpstreamStatus}, []error{ErrRateLimited, ErrUpstreamTimeout}},
+		{"deadline", &UpstreamError{Err: context.DeadlineExceeded}, 0, false, false,
+			[]error{ErrUpstreamTimeout, context.DeadlineExceeded}, []error{ErrUpstreamStatus, ErrRateLimited}},
+		{"net timeout", &UpstreamError{Err: timeoutError{}}, 0, false, false,
+			[]error{ErrUpstreamTimeout}, []error{ErrUpstreamStatus}},
+		{"refused", &UpstreamError{Err: errors.New("connection refused")}, 0, false, false,
+			nil, []error{ErrUpstreamTimeout, ErrUpstreamStatus, ErrRateLimited}},
+		{"layout", errors.Wrap(&ParseError{Source: "career page", Err: errors.New("missing")}, "Failed to parse"), -1, true, false,
+			[]error{ErrLayoutChanged}, []error{ErrUpstreamStatus, ErrUpstreamTimeout}},
+		{"not found", ErrPlayerNotFound, -1, false, false,
+			[]error{ErrPlayerNotFound}, []error{ErrLayoutChanged, ErrUpstreamStatus, ErrInvalidTag}},
+		{"invalid tag", errors.Wrap(&TagError{Platform: PlatformPC, Tag: "Viz", Reason: "missing #"}, "Failed to retrieve profile"), -1, false, true,
+			[]error{ErrInvalidTag}, []error{ErrPlayerNotFound, ErrUpstreamStatus}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ue *UpstreamError
+			if errors.As(tt.err, &ue) != (tt.status >= 0) || (ue != nil && ue.StatusCode != tt.status) {
+				t.Errorf("Expected an UpstreamError with status %d, got %v", tt.status, ue)
+			}
+			var pe *ParseError
+			if errors.As(tt.err, &pe) != tt.parse {
+				t.Errorf("Expected ParseError %v, got %v", tt.parse, pe)
+			}
+			var te *TagError
+			if errors.As(tt.err, &te) != tt.tag || (te != nil && te.Tag != "Viz") {
+				t.Errorf("Expected TagError %v, got %v", tt.tag, te)
+			}
+			for _, target := range tt.is {
+				if !errors.Is(tt.err, target) {
+					t.Errorf("Expected %v to match %v", tt.err, target)
+				}
+			}
+			for _, target := range tt.isNot {
+				if errors.Is(tt.err, target) {
+					t.Errorf("Expected %v not to match %v", tt.err, target)
+				}
+			}
+		})
+	}
+}
+
+func TestUpstreamErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		delay  time.Duration
+		want   []error
+	}{
+		{"unavailable", http.StatusServiceUnavailable, 0, []error{ErrUpstreamStatus}},
+		{"rate limited", http.StatusTooManyRequests, 0, []error{ErrUpstreamStatus, ErrRateLimited}},
+		{"timeout", http.StatusOK, time.Second, []error{ErrUpstreamTimeout}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				select {
+				case <-time.After(tt.delay):
+				case <-r.Context().Done():
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			_, err := NewClient(WithBaseURL(srv.URL), WithRetryPolicy(NoRetry)).StatsContext(ctx, PlatformPC, "Viz-1213")
+
+			var ue *UpstreamError
+			if !errors.As(err, &ue) {
+				t.Fatalf("Expected an UpstreamError, got %v", err)
+			}
+			for _, target := range tt.want {
+				if !errors.Is(err, target) {
+					t.Errorf("Expected %v to match %v", err, target)
+				}
+			}
+		})
+	}
+}
+
+func TestCareerNotFoundStatus(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	_, err := NewClient(WithBaseURL(srv.URL)).Stats(PlatformPC, "Viz-1213")
+	if err != ErrPlayerNotFound {
+		t.Errorf("Expected ErrPlayerNotFound, got %v", err)
+	}
+}
+
+func TestParseErrorIsLayoutChanged(t *testing.T) {
+	_, err := ParseAccounts(errReader{})
+	if !errors.Is(err, ErrLayoutChanged) {
+		t.Errorf("Expected %v to match ErrLayoutChanged", err)
+	}
+}
+
+// errReader is an io.Reader that always fails
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) { return 0, errors.New("read failed") }
@@ -0,0 +1,155 @@
+//go:build ignore
+
+// gen_catalog regenerates catalog.go from the keys already in the Catalog and
+// the career stats found on the career pages in the passed directories, so
+// newly saved pages extend the catalog. It is run by go generate
+package main
+
+import (
+	"bytes"
+	"flag"
+	"go/format"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"text/template"
+
+	"github.com/s32x/ovrstat/ovrstat"
+)
+
+// categoryNames maps every category to the name of its constant
+var categoryNames = map[string]string{
+	ovrstat.CategoryAssists:       "CategoryAssists",
+	ovrstat.CategoryAverage:       "CategoryAverage",
+	ovrstat.CategoryBest:          "CategoryBest",
+	ovrstat.CategoryCombat:        "CategoryCombat",
+	ovrstat.CategoryDeaths:        "CategoryDeaths",
+	ovrstat.CategoryHeroSpecific:  "CategoryHeroSpecific",
+	ovrstat.CategoryGame:          "CategoryGame",
+	ovrstat.CategoryMatchAwards:   "CategoryMatchAwards",
+	ovrstat.CategoryMiscellaneous: "CategoryMiscellaneous",
+}
+
+// unitNames maps every unit to the name of its constant
+var unitNames = map[ovrstat.Unit]string{
+	ovrstat.UnitCount:   "UnitCount",
+	ovrstat.UnitPercent: "UnitPercent",
+	ovrstat.UnitSeconds: "UnitSeconds",
+	ovrstat.UnitText:    "UnitText",
+}
+
+var tmpl = template.Must(template.New("catalog").Funcs(template.FuncMap{
+	"category": func(c string) string {
+		if name, ok := categoryNames[c]; ok {
+			return name
+		}
+		return strconv.Quote(c)
+	},
+	"unit":   func(u ovrstat.Unit) string { return unitNames[u] },
+	"sorted": sorted,
+}).Parse(`// Code generated by gen_catalog.go; DO NOT EDIT.
+
+package ovrstat
+
+// Catalog lists the career stat keys known to be emitted by the career page
+// for every hero, mapped to the unit their values are measured in. Hero
+// specific stats vary per hero and are not listed
+var Catalog = map[string]map[string]Unit{
+{{- range $category := sorted . }}
+	{{ category $category }}: {
+	{{- $keys := index $ $category }}
+	{{- range $key := sorted $keys }}
+		{{ printf "%q" $key }}: {{ unit (index $keys $key) }},
+	{{- end }}
+	},
+{{- end }}
+}
+
+// LookupStat returns the unit of a known career stat and whether the stat is
+// present in the Catalog
+func LookupStat(category, key string) (Unit, bool) {
+	u, ok := Catalog[category][key]
+	return u, ok
+}
+`))
+
+func main() {
+	out := flag.String("o", "catalog.go", "file the catalog is written to")
+	flag.Parse()
+
+	catalog := make(map[string]map[string]ovrstat.Unit)
+	for category, keys := range ovrstat.Catalog {
+		catalog[category] = make(map[string]ovrstat.Unit)
+		for key, unit := range keys {
+			catalog[category][key] = unit
+		}
+	}
+	for _, dir := range flag.Args() {
+		paths, err := filepath.Glob(filepath.Join(dir, "*.html"))
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, path := range paths {
+			if err := addPage(catalog, path); err != nil {
+				log.Printf("Skipping %s: %v", path, err)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, catalog); err != nil {
+		log.Fatal(err)
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*out, src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// addPage adds the career stats of a career page missing from the catalog
+func addPage(catalog map[string]map[string]ovrstat.Unit, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	ps, err := ovrstat.ParseProfile(f)
+	if err != nil {
+		return err
+	}
+
+	for _, sc := range []ovrstat.StatsCollection{ps.QuickPlayStats.StatsCollection, ps.CompetitiveStats.StatsCollection} {
+		for _, cs := range sc.CareerStats {
+			for _, s := range cs.Stats {
+				// Hero specific stats vary per hero and values that aren't
+				// numbers can't be typed
+				if s.Category == ovrstat.CategoryHeroSpecific || s.Unit == ovrstat.UnitText {
+					continue
+				}
+				if catalog[s.Category] == nil {
+					catalog[s.Category] = make(map[string]ovrstat.Unit)
+				}
+				if _, ok := catalog[s.Category][s.Key]; !ok {
+					catalog[s.Category][s.Key] = s.Unit
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// sorted returns the keys of the catalog or of one of its categories in order
+func sorted(m interface{}) []string {
+	var keys []string
+	for _, k := range reflect.ValueOf(m).MapKeys() {
+		keys = append(keys, k.String())
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,117 @@
+package ovrstat
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// Upstream endpoints reported in UpstreamEvents
+const (
+	EndpointCareer = "career"
+	EndpointSearch = "search"
+)
+
+// UpstreamEvent describes a single completed upstream request attempt
+type UpstreamEvent struct {
+	Endpoint   string        // EndpointCareer or EndpointSearch
+	URL        string        // The requested URL
+	Attempt    int           // The attempt number, starting at 1
+	StatusCode int           // The response status, zero if none was received
+	Bytes      int64         // The number of response body bytes read
+	Duration   time.Duration // The time from sending the request to closing its body
+	Err        error         // The transport error, if any
+}
+
+// LookupEvent describes a single completed stats lookup
+type LookupEvent struct {
+	Player   PlayerID      // The looked up player
+	ID       int           // The requested account ID, zero if none
+	Stats    *PlayerStats  // The retrieved stats, nil if the lookup failed
+	Duration time.Duration // The time taken by the lookup
+	Err      error         // The error the lookup failed with, if any
+}
+
+// Hooks are called by a Client as it performs lookups, e.g. to record
+// metrics. Any of the hooks may be nil
+type Hooks struct {
+	// Upstream is called after every upstream request attempt
+	Upstream func(ctx context.Context, ev UpstreamEvent)
+
+	// Parse is called after every career page is parsed
+	Parse func(ctx context.Context, d time.Duration, err error)
+
+	// LookupStart and Lookup are called as every stats lookup starts and
+	// completes upstream. A lookup shared by coalesced callers is reported
+	// once
+	LookupStart func(ctx context.Context, pid PlayerID, id int)
+	Lookup      func(ctx context.Context, ev LookupEvent)
+}
+
+// WithHooks adds hooks that are called as the client performs lookups
+func WithHooks(h Hooks) Option {
+	return func(c *Client) { c.hooks = append(c.hooks, h) }
+}
+
+// upstream logs an upstream request attempt and calls every Upstream hook
+func (c *Client) upstream(ctx context.Context, ev UpstreamEvent) {
+	c.logUpstream(ctx, ev)
+	for _, h := range c.hooks {
+		if h.Upstream != nil {
+			h.Upstream(ctx, ev)
+		}
+	}
+}
+
+// parsed logs a parsed career page and calls every Parse hook
+func (c *Client) parsed(ctx context.Context, d time.Duration, err error) {
+	c.logParse(ctx, d, err)
+	for _, h := range c.hooks {
+		if h.Parse != nil {
+			h.Parse(ctx, d, err)
+		}
+	}
+}
+
+// lookupStarted calls every LookupStart hook
+func (c *Client) lookupStarted(ctx context.Context, pid PlayerID, id int) {
+	for _, h := range c.hooks {
+		if h.LookupStart != nil {
+			h.LookupStart(ctx, pid, id)
+		}
+	}
+}
+
+// lookedUp calls every Lookup hook
+func (c *Client) lookedUp(ctx context.Context, ev LookupEvent) {
+	for _, h := range c.hooks {
+		if h.Lookup != nil {
+			h.Lookup(ctx, ev)
+		}
+	}
+}
+
+// observedBody counts the bytes read from a response body, reporting the
+// completed request once the body is closed
+type observedBody struct {
+	io.ReadCloser
+	bytes   int64
+	onClose func(bytes int64)
+}
+
+// Read reads from the body, counting the bytes read
+func (b *observedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.bytes += int64(n)
+	return n, err
+}
+
+// Close closes the body and reports the request
+func (b *observedBody) Close() error {
+	err := b.ReadCloser.Close()
+	if b.onClose != nil {
+		b.onClose(b.bytes)
+		b.onClose = nil
+	}
+	return err
+}
@@ -0,0 +1,62 @@
+package ovrstat
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHooks(t *testing.T) {
+	var mu sync.Mutex
+	var events []UpstreamEvent
+	var parses, starts int
+	var lookups []LookupEvent
+	c := fixtureClient(t, "pc-public.html", "viz.json")
+	WithHooks(Hooks{
+		Upstream: func(ctx context.Context, ev UpstreamEvent) {
+			mu.Lock()
+			defer mu.Unlock()
+			events = append(events, ev)
+		},
+		Parse: func(ctx context.Context, d time.Duration, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			parses++
+		},
+		LookupStart: func(ctx context.Context, pid PlayerID, id int) {
+			mu.Lock()
+			defer mu.Unlock()
+			starts++
+		},
+		Lookup: func(ctx context.Context, ev LookupEvent) {
+			mu.Lock()
+			defer mu.Unlock()
+			lookups = append(lookups, ev)
+		},
+	})(c)
+
+	if _, err := c.PCStats("Viz-1213"); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(events) != 2 || parses != 1 {
+		t.Fatalf("Expected 2 upstream events and 1 parse, got %d and %d", len(events), parses)
+	}
+	seen := make(map[string]bool)
+	for _, ev := range events {
+		seen[ev.Endpoint] = true
+		if ev.StatusCode != 200 || ev.Bytes == 0 || ev.Attempt != 1 {
+			t.Errorf("Unexpected %s event %+v", ev.Endpoint, ev)
+		}
+	}
+	if !seen[EndpointCareer] || !seen[EndpointSearch] {
+		t.Errorf("Expected career and search events, got %+v", events)
+	}
+	if starts != 1 || len(lookups) != 1 || lookups[0].Stats == nil || lookups[0].Err != nil ||
+		lookups[0].Player.URLName() != "Viz-1213" || lookups[0].Duration <= 0 {
+		t.Errorf("Expected a single successful lookup event, got %d starts and %+v", starts, lookups)
+	}
+}
@@ -0,0 +1,109 @@
+package ovrstat
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// RequestIDHeader is the header a contexts request ID is forwarded upstream in
+const RequestIDHeader = "X-Request-ID"
+
+// requestIDKey is the context key request IDs are stored under
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of ctx carrying the passed request ID, which is
+// forwarded on upstream requests and attached to log records
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID carried by ctx, if any
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// WithLogger sets the logger the client reports upstream requests, retries and
+// parse failures to. Nothing is logged by default
+func WithLogger(l *slog.Logger) Option {
+	return func(c *Client) { c.logger = l }
+}
+
+// LogHandler wraps a slog.Handler, adding the request ID carried by the
+// context of each record as a request_id attribute
+func LogHandler(h slog.Handler) slog.Handler {
+	return &requestIDHandler{Handler: h}
+}
+
+// requestIDHandler is the slog.Handler returned by LogHandler
+type requestIDHandler struct {
+	slog.Handler
+}
+
+// Handle adds the records request ID and passes it to the wrapped handler
+func (h *requestIDHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+// WithAttrs returns a handler with the passed attributes added
+func (h *requestIDHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &requestIDHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+// WithGroup returns a handler nesting attributes in the passed group
+func (h *requestIDHandler) WithGroup(name string) slog.Handler {
+	return &requestIDHandler{Handler: h.Handler.WithGroup(name)}
+}
+
+// logUpstream logs a completed upstream request attempt
+func (c *Client) logUpstream(ctx context.Context, ev UpstreamEvent) {
+	if c.logger == nil {
+		return
+	}
+	level := slog.LevelInfo
+	attrs := []slog.Attr{
+		slog.String("endpoint", ev.Endpoint),
+		slog.String("url", ev.URL),
+		slog.Int("attempt", ev.Attempt),
+		slog.Int("status", ev.StatusCode),
+		slog.Int64("bytes", ev.Bytes),
+		slog.Duration("duration", ev.Duration),
+	}
+	if ev.Err != nil || ev.StatusCode != http.StatusOK {
+		level = slog.LevelWarn
+	}
+	if ev.Err != nil {
+		attrs = append(attrs, slog.String("error", ev.Err.Error()))
+	}
+	c.logger.LogAttrs(ctx, level, "upstream request", attrs...)
+}
+
+// logRetry logs an upstream request that is about to be retried
+func (c *Client) logRetry(ctx context.Context, endpoint string, attempt int, delay time.Duration, err error) {
+	if c.logger == nil {
+		return
+	}
+	c.logger.LogAttrs(ctx, slog.LevelInfo, "retrying upstream request",
+		slog.String("endpoint", endpoint),
+		slog.Int("attempt", attempt),
+		slog.Duration("delay", delay),
+		slog.String("error", err.Error()))
+}
+
+// logParse logs a parsed career page, warning if parsing failed
+func (c *Client) logParse(ctx context.Context, d time.Duration, err error) {
+	if c.logger == nil {
+		return
+	}
+	if err != nil {
+		c.logger.LogAttrs(ctx, slog.LevelWarn, "career page parse failed",
+			slog.Duration("duration", d), slog.String("error", err.Error()))
+		return
+	}
+	c.logger.LogAttrs(ctx, slog.LevelDebug, "career page parsed", slog.Duration("duration", d))
+}
@@ -0,0 +1,67 @@
+package ovrstat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestRequestIDLogging(t *testing.T) {
+	var mu sync.Mutex
+	var forwarded []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		forwarded = append(forwarded, r.Header.Get(RequestIDHeader))
+		mu.Unlock()
+		if strings.HasPrefix(r.URL.Path, "/search/") {
+			http.ServeFile(w, r, filepath.Join("testdata", "search", "viz.json"))
+			return
+		}
+		http.ServeFile(w, r, filepath.Join("testdata", "career", "pc-public.html"))
+	}))
+	defer srv.Close()
+
+	var buf bytes.Buffer
+	logger := slog.New(LogHandler(slog.NewJSONHandler(&buf, nil)))
+	c := NewClient(WithBaseURL(srv.URL+"/career"), WithAPIURL(srv.URL+"/search/"), WithLogger(logger))
+
+	ctx := WithRequestID(context.Background(), "abc123")
+	if _, err := c.PCStatsContext(ctx, "Viz-1213"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The request ID is forwarded on both upstream requests
+	mu.Lock()
+	defer mu.Unlock()
+	if len(forwarded) != 2 || forwarded[0] != "abc123" || forwarded[1] != "abc123" {
+		t.Errorf("Expected the request ID to be forwarded upstream, got %q", forwarded)
+	}
+
+	// Every upstream request is logged with the request ID attached
+	endpoints := make(map[string]bool)
+	for _, line := range bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n")) {
+		var rec map[string]interface{}
+		if err := json.Unmarshal(line, &rec); err != nil {
+			t.Fatalf("Failed to decode log record %s: %v", line, err)
+		}
+		if rec["request_id"] != "abc123" {
+			t.Errorf("Expected request_id on every record, got %s", line)
+		}
+		if rec["msg"] == "upstream request" {
+			endpoints[rec["endpoint"].(string)] = true
+			if rec["status"] != float64(200) || rec["bytes"] == float64(0) {
+				t.Errorf("Expected status and bytes to be logged, got %s", line)
+			}
+		}
+	}
+	if !endpoints[EndpointCareer] || !endpoints[EndpointSearch] {
+		t.Errorf("Expected both upstream requests to be logged, got %v", endpoints)
+	}
+}
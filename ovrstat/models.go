@@ -3,6 +3,8 @@ package ovrstat
 // PlayerStats holds all stats on a specified Overwatch player
 type PlayerStats struct {
 	Icon             string                     `json:"icon"`
+	ID               int                        `json:"id"`  // The ID of the account the lookup resolved to
+	Tag              string                     `json:"tag"` // The canonical tag of the account, e.g. Viz-1213
 	Name             string                     `json:"name"`
 	Level            int                        `json:"level"`
 	LevelIcon        string                     `json:"levelIcon"`
@@ -15,6 +17,9 @@ type PlayerStats struct {
 	QuickPlayStats   QuickPlayStatsCollection   `json:"quickPlayStats"`
 	CompetitiveStats CompetitiveStatsCollection `json:"competitiveStats"`
 	Private          bool                       `json:"private"`
+
+	// Report lists the page elements found while parsing the career page
+	Report *ParseReport `json:"-"`
 }
 
 type Rating struct {
@@ -38,19 +43,26 @@ type QuickPlayStatsCollection struct {
 	StatsCollection
 }
 
-// TopHeroStats holds basic stats for each hero
+// TopHeroStats holds basic stats for each hero. TimePlayed is the text shown on
+// the career page and TimePlayedDuration its parsed value
 type TopHeroStats struct {
-	TimePlayed          string  `json:"timePlayed"`
-	GamesWon            int     `json:"gamesWon"`
-	WinPercentage       int     `json:"winPercentage"`
-	WeaponAccuracy      int     `json:"weaponAccuracy"`
-	EliminationsPerLife float64 `json:"eliminationsPerLife"`
-	MultiKillBest       int     `json:"multiKillBest"`
-	ObjectiveKills      float64 `json:"objectiveKills"`
+	TimePlayed          string   `json:"timePlayed"`
+	TimePlayedDuration  Duration `json:"timePlayedSeconds"`
+	GamesWon            int      `json:"gamesWon"`
+	WinPercentage       int      `json:"winPercentage"`
+	WeaponAccuracy      int      `json:"weaponAccuracy"`
+	EliminationsPerLife float64  `json:"eliminationsPerLife"`
+	MultiKillBest       int      `json:"multiKillBest"`
+	ObjectiveKills      float64  `json:"objectiveKills"`
 }
 
-// CareerStats holds very detailed stats for each hero
+// CareerStats holds very detailed stats for each hero. Stats holds every stat
+// as a typed value, the category maps hold the same stats in their original
+// untyped form. The maps are only populated by clients created with
+// WithLegacyCareerStats, which also encode CareerStats in the original JSON
+// shape, or by Legacy
 type CareerStats struct {
+	Stats         []Stat                 `json:"stats,omitempty"`
 	Assists       map[string]interface{} `json:"assists"`
 	Average       map[string]interface{} `json:"average"`
 	Best          map[string]interface{} `json:"best"`
@@ -60,6 +72,8 @@ type CareerStats struct {
 	Game          map[string]interface{} `json:"game"`
 	MatchAwards   map[string]interface{} `json:"matchAwards"`
 	Miscellaneous map[string]interface{} `json:"miscellaneous"`
+
+	legacy bool // Encode in the original JSON shape
 }
 
 // Platform represents a response from the search-by-name api request
@@ -0,0 +1,108 @@
+package ovrstat
+
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// ParseProfile parses an already downloaded career page into a PlayerStats.
+// Name and Prestige are not present on the career page and are left unset,
+// use ResolveAccount to populate them from an account-by-name API response.
+// Options unrelated to parsing are ignored
+func ParseProfile(r io.Reader, opts ...Option) (*PlayerStats, error) {
+	return NewClient(opts...).ParseProfile(r)
+}
+
+// ParseAccounts decodes an already downloaded account-by-name API response
+func ParseAccounts(r io.Reader) ([]Platform, error) {
+	var platforms []Platform
+	if err := json.NewDecoder(r).Decode(&platforms); err != nil {
+		return nil, &ParseError{Source: "platform API response", Err: err}
+	}
+	return platforms, nil
+}
+
+// ResolveAccount finds the account matching the passed platform and tag in an
+// account-by-name API response and sets ID, Tag, Name and Prestige on ps from
+// it
+func ResolveAccount(ps *PlayerStats, platform, tag string, accounts []Platform, opts ...Option) error {
+	return NewClient(opts...).ResolveAccount(ps, platform, tag, accounts)
+}
+
+// ParseProfile parses an already downloaded career page into a PlayerStats
+// using the clients parsing options
+func (c *Client) ParseProfile(r io.Reader) (*PlayerStats, error) {
+	// Parses the career page into a goquery document
+	pd, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return nil, &ParseError{Source: "career page", Err: err}
+	}
+
+	// Checks if profile not found, site still returns 200 in this case
+	if pd.Find("h1.u-align-center").First().Text() == "Profile Not Found" {
+		return nil, ErrPlayerNotFound
+	}
+
+	// Verifies the elements the parser relies on are present so a redesigned
+	// page fails loudly instead of producing empty stats
+	report := new(ParseReport)
+	report.check(pd, mastheadChecks)
+	private := pd.Find("p.masthead-permission-level-text").First().Text() == "Private Profile"
+	if !private {
+		report.check(pd, statsChecks)
+	}
+	if missing := report.Missing(); len(missing) > 0 {
+		return nil, &ParseError{Source: "career page", Missing: missing}
+	}
+
+	// Scrapes all stats for the passed user and sets struct member data
+	ps := parseGeneralInfo(pd.Find("div.masthead").First())
+	ps.Report = report
+
+	if private {
+		ps.Private = true
+		return &ps, nil
+	}
+
+	parseDetailedStats(pd.Find("div#quickplay").First(), &ps.QuickPlayStats.StatsCollection, c.legacyStats)
+	parseDetailedStats(pd.Find("div#competitive").First(), &ps.CompetitiveStats.StatsCollection, c.legacyStats)
+
+	competitiveSeason, _ := pd.Find("div[data-competitive-season]").Attr("data-competitive-season")
+
+	if competitiveSeason != "" {
+		competitiveSeason, _ := strconv.Atoi(competitiveSeason)
+
+		ps.CompetitiveStats.Season = &competitiveSeason
+	}
+
+	return &ps, nil
+}
+
+// ResolveAccount finds the account matching the passed platform and tag in an
+// account-by-name API response, choosing between several using the clients
+// Resolution, and sets ID, Tag, Name and Prestige on ps from it
+func (c *Client) ResolveAccount(ps *PlayerStats, platform, tag string, accounts []Platform) error {
+	return c.resolveStats(ps, platform, tag, 0, accounts)
+}
+
+// resolveStats resolves the account a lookup refers to, or the account with
+// the passed id if non-zero, and sets ID, Tag, Name and Prestige on ps from it
+func (c *Client) resolveStats(ps *PlayerStats, platform, tag string, id int, accounts []Platform) error {
+	p, err := c.resolveAccount(platform, tag, id, accounts)
+	if err != nil {
+		return err
+	}
+	setAccount(ps, p)
+	return nil
+}
+
+// setAccount sets the fields of ps taken from the players account
+func setAccount(ps *PlayerStats, p Platform) {
+	ps.ID = p.ID
+	ps.Tag = accountTag(p)
+	ps.Name = p.Name
+	ps.Prestige = prestige(p)
+}
@@ -0,0 +1,51 @@
+package ovrstat
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseProfileOffline(t *testing.T) {
+	page, err := os.Open(filepath.Join("testdata", "career", "pc-public.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer page.Close()
+	search, err := os.Open(filepath.Join("testdata", "search", "viz.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer search.Close()
+
+	ps, err := ParseProfile(page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	accounts, err := ParseAccounts(search)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ResolveAccount(ps, PlatformPC, "Viz-1213", accounts); err != nil {
+		t.Fatal(err)
+	}
+
+	// Parsing offline must match a full lookup of the same fixtures
+	assertGolden(t, "pc-public", ps)
+
+	if !ps.Report.OK() || len(ps.Report.Warnings()) > 0 {
+		t.Errorf("Expected all elements to be found, got %+v", ps.Report.Checks)
+	}
+}
+
+func TestParseProfileNotFound(t *testing.T) {
+	page, err := os.Open(filepath.Join("testdata", "career", "not-found.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer page.Close()
+
+	if _, err := ParseProfile(page); err != ErrPlayerNotFound {
+		t.Errorf("Expected ErrPlayerNotFound, got %v", err)
+	}
+}
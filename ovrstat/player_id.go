@@ -0,0 +1,144 @@
+package ovrstat
+
+import (
+	"net/url"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// PlayerID identifies a player on a platform
+type PlayerID struct {
+	Platform string
+
+	// Name is the BattleTag name, PSN online ID, Xbox gamertag or Switch
+	// nickname of the player
+	Name string
+
+	// Discriminator is the number of a BattleTag or the hash of a Switch ID,
+	// empty on other platforms
+	Discriminator string
+}
+
+// ParsePlayerID parses and validates a tag as used on the passed platform.
+// BattleTags are accepted as Name#1234 or Name-1234, PSN online IDs and Xbox
+// gamertags as is and Switch IDs as Name-hash. Tags may be URL escaped and
+// surrounding whitespace is ignored. Invalid tags return a TagError
+func ParsePlayerID(platform, tag string) (PlayerID, error) {
+	raw := tag
+	tag = strings.TrimSpace(tag)
+	if strings.Contains(tag, "%") {
+		if unescaped, err := url.PathUnescape(tag); err == nil {
+			tag = strings.TrimSpace(unescaped)
+		}
+	}
+	invalid := func(reason string) (PlayerID, error) {
+		return PlayerID{}, &TagError{Platform: platform, Tag: raw, Reason: reason}
+	}
+
+	id := PlayerID{Platform: platform, Name: tag}
+	switch platform {
+	case PlatformPC:
+		i := strings.LastIndexAny(tag, "#-")
+		if i < 0 {
+			return invalid("BattleTags must end with their number, e.g. Name#1234")
+		}
+		id.Name, id.Discriminator = tag[:i], tag[i+1:]
+		if n := utf8.RuneCountInString(id.Name); n < 3 || n > 12 {
+			return invalid("BattleTag names are 3 to 12 characters long")
+		}
+		if r, _ := utf8.DecodeRuneInString(id.Name); unicode.IsDigit(r) {
+			return invalid("BattleTag names can't start with a number")
+		}
+		if !allRunes(id.Name, isAlnum) {
+			return invalid("BattleTag names only contain letters and numbers")
+		}
+		if n := len(id.Discriminator); n < 4 || n > 8 || !allRunes(id.Discriminator, isASCIIDigit) {
+			return invalid("BattleTag numbers are 4 to 8 digits long")
+		}
+	case PlatformPSN:
+		if n := len(tag); n < 3 || n > 16 {
+			return invalid("PSN online IDs are 3 to 16 characters long")
+		}
+		if !allRunes(tag, func(r rune) bool { return r < utf8.RuneSelf && (isAlnum(r) || r == '-' || r == '_') }) {
+			return invalid("PSN online IDs only contain letters, numbers, hyphens and underscores")
+		}
+	case PlatformXBL:
+		if n := utf8.RuneCountInString(tag); n < 1 || n > 15 {
+			return invalid("Xbox gamertags are 1 to 15 characters long")
+		}
+		if !allRunes(tag, func(r rune) bool { return isAlnum(r) || r == ' ' }) {
+			return invalid("Xbox gamertags only contain letters, numbers and spaces")
+		}
+	case PlatformNS:
+		i := strings.LastIndex(tag, "-")
+		if i < 0 {
+			return invalid("Switch IDs must end with their hash, e.g. Name-0123456789abcdef0123456789abcdef")
+		}
+		id.Name, id.Discriminator = tag[:i], strings.ToLower(tag[i+1:])
+		if id.Name == "" || !allRunes(id.Name, func(r rune) bool { return unicode.IsPrint(r) && r != '/' }) {
+			return invalid("Switch IDs must start with the players nickname")
+		}
+		if len(id.Discriminator) != 32 || !allRunes(id.Discriminator, isHex) {
+			return invalid("Switch ID hashes are 32 hexadecimal digits")
+		}
+	default:
+		return PlayerID{}, ErrInvalidPlatform
+	}
+	return id, nil
+}
+
+// String returns the canonical form of the tag, e.g. Viz#1213
+func (id PlayerID) String() string {
+	if id.Platform == PlatformPC {
+		return id.Name + "#" + id.Discriminator
+	}
+	return id.URLName()
+}
+
+// URLName returns the tag as used in career page URLs and the URLName of
+// search results, e.g. Viz-1213
+func (id PlayerID) URLName() string {
+	if id.Discriminator == "" {
+		return id.Name
+	}
+	return id.Name + "-" + id.Discriminator
+}
+
+// careerPath returns the escaped path of the players career page
+func (id PlayerID) careerPath() string {
+	return "/" + id.Platform + "/" + url.PathEscape(id.URLName())
+}
+
+// searchName returns the escaped name the search API is queried with
+func (id PlayerID) searchName() string {
+	if id.Platform == PlatformPC {
+		return url.PathEscape(id.String())
+	}
+	return url.PathEscape(id.Name)
+}
+
+// allRunes reports whether every rune of s satisfies f
+func allRunes(s string, f func(rune) bool) bool {
+	for _, r := range s {
+		if !f(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// isAlnum reports whether r is a letter or digit in any script
+func isAlnum(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// isASCIIDigit reports whether r is a digit from 0 to 9
+func isASCIIDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+// isHex reports whether r is a lowercase hexadecimal digit
+func isHex(r rune) bool {
+	return isASCIIDigit(r) || (r >= 'a' && r <= 'f')
+}
@@ -0,0 +1,63 @@
+package ovrstat
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestParsePlayerID(t *testing.T) {
+	const hash = "70af1a16ae4913bde139d46edb43df55"
+	tests := []struct {
+		platform string
+		tag      string
+		canon    string // The canonical form, empty if the tag is invalid
+		career   string
+		search   string
+	}{
+		{PlatformPC, "Viz-1213", "Viz#1213", "/pc/Viz-1213", "Viz%231213"},
+		{PlatformPC, " Viz#1213 ", "Viz#1213", "/pc/Viz-1213", "Viz%231213"},
+		{PlatformPC, "Viz%231213", "Viz#1213", "/pc/Viz-1213", "Viz%231213"},
+		{PlatformPC, "Zoë-21842", "Zoë#21842", "/pc/Zo%C3%AB-21842", "Zo%C3%AB%2321842"},
+		{PlatformPC, "Viz", "", "", ""},
+		{PlatformPC, "Viz#12", "", "", ""},
+		{PlatformPC, "1Viz#1213", "", "", ""},
+		{PlatformPC, "V#1213", "", "", ""},
+		{PlatformPC, "Viz/../x-1213", "", "", ""},
+		{PlatformPSN, "TayuyaBreast", "TayuyaBreast", "/psn/TayuyaBreast", "TayuyaBreast"},
+		{PlatformPSN, "Tayuya_Breast-2", "Tayuya_Breast-2", "/psn/Tayuya_Breast-2", "Tayuya_Breast-2"},
+		{PlatformPSN, "Tayuya Breast", "", "", ""},
+		{PlatformPSN, "ab", "", "", ""},
+		{PlatformXBL, "Lt Evolution", "Lt Evolution", "/xbl/Lt%20Evolution", "Lt%20Evolution"},
+		{PlatformXBL, "Lt%20Evolution", "Lt Evolution", "/xbl/Lt%20Evolution", "Lt%20Evolution"},
+		{PlatformXBL, "Lt_Evolution", "", "", ""},
+		{PlatformXBL, "", "", "", ""},
+		{PlatformNS, "Mario-" + hash, "Mario-" + hash, "/nintendo-switch/Mario-" + hash, "Mario"},
+		{PlatformNS, "Mario-70AF1A16AE4913BDE139D46EDB43DF55", "Mario-" + hash, "/nintendo-switch/Mario-" + hash, "Mario"},
+		{PlatformNS, "Mario", "", "", ""},
+		{PlatformNS, "Mario-1234", "", "", ""},
+		{PlatformNS, "-" + hash, "", "", ""},
+	}
+	for _, tt := range tests {
+		id, err := ParsePlayerID(tt.platform, tt.tag)
+		if tt.canon == "" {
+			var te *TagError
+			if !errors.As(err, &te) || !errors.Is(err, ErrInvalidTag) || te.Reason == "" {
+				t.Errorf("ParsePlayerID(%s, %q) = %v, %v, want a TagError", tt.platform, tt.tag, id, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParsePlayerID(%s, %q) failed: %v", tt.platform, tt.tag, err)
+			continue
+		}
+		if id.String() != tt.canon || id.careerPath() != tt.career || id.searchName() != tt.search {
+			t.Errorf("ParsePlayerID(%s, %q) = %q %q %q, want %q %q %q", tt.platform, tt.tag,
+				id, id.careerPath(), id.searchName(), tt.canon, tt.career, tt.search)
+		}
+	}
+
+	if _, err := ParsePlayerID("wii", "Mario"); err != ErrInvalidPlatform {
+		t.Errorf("Expected ErrInvalidPlatform, got %v", err)
+	}
+}
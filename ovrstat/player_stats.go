@@ -1,14 +1,13 @@
 package ovrstat
 
 import (
-	"encoding/json"
-	"fmt"
-	"math"
+	"context"
 	"net/http"
 	"net/url"
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 	"unicode"
 
 	"github.com/PuerkitoBio/goquery"
@@ -32,149 +31,123 @@ const (
 	PlatformNS = "nintendo-switch"
 )
 
-var (
-	// ErrPlayerNotFound is thrown when a player doesn't exist
-	ErrPlayerNotFound = errors.New("Player not found")
-
-	// ErrInvalidPlatform is thrown when the passed params are incorrect
-	ErrInvalidPlatform = errors.New("Invalid platform")
-)
-
-// Stats retrieves player stats
+// Stats retrieves player stats using the DefaultClient
 // Universal method if you don't need to differentiate it
 func Stats(platform, tag string) (*PlayerStats, error) {
-	switch platform {
-	case PlatformPC:
-		return PCStats(tag) // Perform a stats lookup for PC
-	case PlatformPSN, PlatformXBL, PlatformNS:
-		return ConsoleStats(platform, tag) // Perform a stats lookup for Console
-	default:
-		return nil, ErrInvalidPlatform
-	}
+	return DefaultClient.Stats(platform, tag)
 }
 
-// ConsoleStats retrieves player stats for Console
+// ConsoleStats retrieves player stats for Console using the DefaultClient
 func ConsoleStats(platform, tag string) (*PlayerStats, error) {
-	return playerStats(fmt.Sprintf("/%s/%s", platform, tag), platform)
+	return DefaultClient.ConsoleStats(platform, tag)
 }
 
-// PCStats retrieves player stats for PC
+// PCStats retrieves player stats for PC using the DefaultClient
 func PCStats(tag string) (*PlayerStats, error) {
-	return playerStats(fmt.Sprintf("/pc/%s", tag), "pc")
+	return DefaultClient.PCStats(tag)
 }
 
-// playerStats retrieves all Overwatch statistics for a given player
-func playerStats(profilePath string, platform string) (*PlayerStats, error) {
-	// Create the profile url for scraping
-	url := baseURL + profilePath
-
-	// Perform the stats request and decode the response
-	res, err := http.Get(url)
-	if err != nil {
-		return nil, errors.Wrap(err, "Failed to retrieve profile")
-	}
-	defer res.Body.Close()
-
-	// Parses the stats request into a goquery document
-	pd, err := goquery.NewDocumentFromReader(res.Body)
-	if err != nil {
-		return nil, errors.Wrap(err, "Failed to create goquery document")
-	}
-
-	// Checks if profile not found, site still returns 200 in this case
-	if pd.Find("h1.u-align-center").First().Text() == "Profile Not Found" {
-		return nil, ErrPlayerNotFound
-	}
+// StatsContext retrieves player stats using the DefaultClient and the passed
+// context
+func StatsContext(ctx context.Context, platform, tag string) (*PlayerStats, error) {
+	return DefaultClient.StatsContext(ctx, platform, tag)
+}
 
-	// Scrapes all stats for the passed user and sets struct member data
-	ps := parseGeneralInfo(pd.Find("div.masthead").First())
+// ConsoleStatsContext retrieves player stats for Console using the
+// DefaultClient and the passed context
+func ConsoleStatsContext(ctx context.Context, platform, tag string) (*PlayerStats, error) {
+	return DefaultClient.ConsoleStatsContext(ctx, platform, tag)
+}
 
-	// Perform api request
-	var platforms []Platform
+// PCStatsContext retrieves player stats for PC using the DefaultClient and the
+// passed context
+func PCStatsContext(ctx context.Context, tag string) (*PlayerStats, error) {
+	return DefaultClient.PCStatsContext(ctx, tag)
+}
 
-	tagPath := profilePath[strings.LastIndex(profilePath, "/")+1:]
-	apiPath := tagPath
+// StatsByID retrieves the stats of the account with the passed ID using the
+// DefaultClient
+func StatsByID(ctx context.Context, platform, tag string, id int) (*PlayerStats, error) {
+	return DefaultClient.StatsByID(ctx, platform, tag, id)
+}
 
-	if platform == PlatformNS {
-		apiPath = apiPath[0:strings.Index(apiPath, "-")]
-	} else if platform != PlatformPSN {
-		apiPath = strings.Replace(apiPath, "-", "%23", -1)
+// playerStats retrieves all Overwatch statistics for a given player, resolving
+// the account with the passed id if non-zero
+func (c *Client) playerStats(ctx context.Context, pid PlayerID, id int) (*PlayerStats, error) {
+	// Fetch the career page first so missing players fail without searching,
+	// unless an id already chooses the account
+	var ps *PlayerStats
+	if id == 0 {
+		var err error
+		ps, err = c.career(ctx, pid)
+		if err == ErrPlayerNotFound && c.caseInsensitive {
+			// Career URLs are case sensitive, the search API may still find
+			// the player under their canonical tag
+			err = nil
+		}
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	apires, err := http.Get(apiURL + apiPath)
+	platforms, err := c.accounts(ctx, pid)
 	if err != nil {
-		return nil, errors.Wrap(err, "Failed to perform platform API request")
+		return nil, err
 	}
-	defer apires.Body.Close()
-
-	// Decode received JSON
-	if err := json.NewDecoder(apires.Body).Decode(&platforms); err != nil {
-		return nil, errors.Wrap(err, "Failed to decode platform API response")
+	account, err := c.resolveAccount(pid.Platform, pid.URLName(), id, platforms)
+	if err != nil {
+		return nil, err
 	}
 
-	platforms = filterPlatform(tagPath, platform, platforms)
-
-	switch len(platforms) {
-	case 0:
-		// Not found
-		return nil, ErrPlayerNotFound
-	case 1:
-		// Single, exact result
-		p := platforms[0]
-
-		ps.Name = p.Name
-		ps.Prestige = int(math.Floor(float64(p.PlayerLevel) / 100))
-	default:
-		// Matched multiple results (2+), use exact name if possible and the first result
-		var foundMatch bool
-
-		for _, p := range platforms {
-			if p.Name == ps.Name {
-				ps.Prestige = int(math.Floor(float64(p.PlayerLevel) / 100))
-
-				foundMatch = true
-				break
-			}
+	// The stats must come from the career page of the chosen account, which
+	// may differ from the one fetched in case
+	if tag := accountTag(account); ps == nil || tag != pid.URLName() {
+		if ps == nil && id == 0 && tag == pid.URLName() {
+			return nil, ErrPlayerNotFound
 		}
-
-		if !foundMatch {
-			p := platforms[0]
-
-			ps.Name = p.Name
-			ps.Prestige = int(math.Floor(float64(p.PlayerLevel) / 100))
+		apid, err := ParsePlayerID(pid.Platform, tag)
+		if err != nil {
+			return nil, err
+		}
+		if ps, err = c.career(ctx, apid); err != nil {
+			return nil, err
 		}
 	}
-
-	if pd.Find("p.masthead-permission-level-text").First().Text() == "Private Profile" {
-		ps.Private = true
-		return &ps, nil
+	setAccount(ps, account)
+	if ps.Private && c.rejectPrivate {
+		return nil, ErrPrivateProfile
 	}
+	return ps, nil
+}
 
-	parseDetailedStats(pd.Find("div#quickplay").First(), &ps.QuickPlayStats.StatsCollection)
-	parseDetailedStats(pd.Find("div#competitive").First(), &ps.CompetitiveStats.StatsCollection)
-
-	competitiveSeason, _ := pd.Find("div[data-competitive-season]").Attr("data-competitive-season")
-
-	if competitiveSeason != "" {
-		competitiveSeason, _ := strconv.Atoi(competitiveSeason)
-
-		ps.CompetitiveStats.Season = &competitiveSeason
+// career retrieves and parses the career page of a player
+func (c *Client) career(ctx context.Context, pid PlayerID) (*PlayerStats, error) {
+	// Perform the stats request and decode the response
+	res, err := c.get(ctx, EndpointCareer, c.baseURL+pid.careerPath())
+	if err != nil {
+		var ue *UpstreamError
+		if errors.As(err, &ue) && ue.StatusCode == http.StatusNotFound {
+			return nil, ErrPlayerNotFound
+		}
+		return nil, errors.Wrap(err, "Failed to retrieve profile")
 	}
+	defer res.Body.Close()
 
-	return &ps, nil
+	// Scrapes all stats for the passed user
+	start := time.Now()
+	ps, err := c.ParseProfile(res.Body)
+	c.parsed(ctx, time.Since(start), err)
+	return ps, err
 }
 
-// Filters the platform slice to return only matching platforms
-func filterPlatform(tagPath, platform string, platforms []Platform) []Platform {
-	out := make([]Platform, 0)
-
-	for _, p := range platforms {
-		if p.Platform == platform && (platform != PlatformNS || p.URLName == tagPath) {
-			out = append(out, p)
-		}
+// accounts retrieves the accounts the search API returns for a player
+func (c *Client) accounts(ctx context.Context, pid PlayerID) ([]Platform, error) {
+	res, err := c.get(ctx, EndpointSearch, c.apiURL+pid.searchName())
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to perform platform API request")
 	}
-
-	return out
+	defer res.Body.Close()
+	return ParseAccounts(res.Body)
 }
 
 // populateGeneralInfo extracts the users general info and returns it in a
@@ -232,9 +205,9 @@ func parseGeneralInfo(s *goquery.Selection) PlayerStats {
 }
 
 // parseDetailedStats populates the passed stats collection with detailed statistics
-func parseDetailedStats(playModeSelector *goquery.Selection, sc *StatsCollection) {
+func parseDetailedStats(playModeSelector *goquery.Selection, sc *StatsCollection, legacy bool) {
 	sc.TopHeroes = parseHeroStats(playModeSelector.Find("div.progress-category").Parent())
-	sc.CareerStats = parseCareerStats(playModeSelector.Find("div.js-stats").Parent())
+	sc.CareerStats = parseCareerStats(playModeSelector.Find("div.js-stats").Parent(), legacy)
 }
 
 // parseHeroStats : Parses stats for each individual hero and returns a map
@@ -257,6 +230,9 @@ func parseHeroStats(heroStatsSelector *goquery.Selection) map[string]*TopHeroSta
 			switch categoryID {
 			case "021":
 				bhsMap[heroName].TimePlayed = statVal
+				if d, err := ParseDuration(statVal); err == nil {
+					bhsMap[heroName].TimePlayedDuration = Duration(d)
+				}
 			case "039":
 				bhsMap[heroName].GamesWon, _ = strconv.Atoi(statVal)
 			case "3D1":
@@ -275,8 +251,9 @@ func parseHeroStats(heroStatsSelector *goquery.Selection) map[string]*TopHeroSta
 	return bhsMap
 }
 
-// parseCareerStats
-func parseCareerStats(careerStatsSelector *goquery.Selection) map[string]*CareerStats {
+// parseCareerStats parses the career stats of every hero, populating the
+// legacy category maps if requested
+func parseCareerStats(careerStatsSelector *goquery.Selection, legacy bool) map[string]*CareerStats {
 	csMap := make(map[string]*CareerStats)
 	heroMap := make(map[string]string)
 
@@ -307,60 +284,18 @@ func parseCareerStats(careerStatsSelector *goquery.Selection) map[string]*Career
 					case 0:
 						statKey = transformKey(cleanJSONKey(statKV.Text()))
 					case 1:
-						statVal = strings.Replace(statKV.Text(), ",", "", -1) // Removes commas from 1k+ values
+						statRaw := statKV.Text()
+						statVal = strings.Replace(statRaw, ",", "", -1) // Removes commas from 1k+ values
 
 						// Creates stat map if it doesn't exist
 						if csMap[currentHero] == nil {
-							csMap[currentHero] = new(CareerStats)
+							csMap[currentHero] = &CareerStats{legacy: legacy}
 						}
 
-						// Switches on type, creating category stat maps if exists (will omitempty on json marshal)
-						switch statType {
-						case "assists":
-							if csMap[currentHero].Assists == nil {
-								csMap[currentHero].Assists = make(map[string]interface{})
-							}
-							csMap[currentHero].Assists[statKey] = parseType(statVal)
-						case "average":
-							if csMap[currentHero].Average == nil {
-								csMap[currentHero].Average = make(map[string]interface{})
-							}
-							csMap[currentHero].Average[statKey] = parseType(statVal)
-						case "best":
-							if csMap[currentHero].Best == nil {
-								csMap[currentHero].Best = make(map[string]interface{})
-							}
-							csMap[currentHero].Best[statKey] = parseType(statVal)
-						case "combat":
-							if csMap[currentHero].Combat == nil {
-								csMap[currentHero].Combat = make(map[string]interface{})
-							}
-							csMap[currentHero].Combat[statKey] = parseType(statVal)
-						case "deaths":
-							if csMap[currentHero].Deaths == nil {
-								csMap[currentHero].Deaths = make(map[string]interface{})
-							}
-							csMap[currentHero].Deaths[statKey] = parseType(statVal)
-						case "heroSpecific":
-							if csMap[currentHero].HeroSpecific == nil {
-								csMap[currentHero].HeroSpecific = make(map[string]interface{})
-							}
-							csMap[currentHero].HeroSpecific[statKey] = parseType(statVal)
-						case "game":
-							if csMap[currentHero].Game == nil {
-								csMap[currentHero].Game = make(map[string]interface{})
-							}
-							csMap[currentHero].Game[statKey] = parseType(statVal)
-						case "matchAwards":
-							if csMap[currentHero].MatchAwards == nil {
-								csMap[currentHero].MatchAwards = make(map[string]interface{})
-							}
-							csMap[currentHero].MatchAwards[statKey] = parseType(statVal)
-						case "miscellaneous":
-							if csMap[currentHero].Miscellaneous == nil {
-								csMap[currentHero].Miscellaneous = make(map[string]interface{})
-							}
-							csMap[currentHero].Miscellaneous[statKey] = parseType(statVal)
+						cs := csMap[currentHero]
+						cs.Stats = append(cs.Stats, newStat(statType, statKey, statRaw))
+						if legacy {
+							cs.addLegacy(statType, statKey, statVal)
 						}
 					}
 				})
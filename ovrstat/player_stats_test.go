@@ -0,0 +1,137 @@
+package ovrstat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+// fixtureClient returns a Client whose upstream serves the passed career page
+// and search result fixtures from testdata
+func fixtureClient(t *testing.T, career, search string) *Client {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/career/"):
+			http.ServeFile(w, r, filepath.Join("testdata", "career", career))
+		case strings.HasPrefix(r.URL.Path, "/search/"):
+			http.ServeFile(w, r, filepath.Join("testdata", "search", search))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return NewClient(
+		WithBaseURL(srv.URL+"/career"),
+		WithAPIURL(srv.URL+"/search/"),
+	)
+}
+
+// assertGolden compares v encoded as JSON against the named golden file,
+// rewriting the golden file instead when the -update flag is passed
+func assertGolden(t *testing.T, name string, v interface{}) {
+	t.Helper()
+	got, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = append(got, '\n')
+
+	path := filepath.Join("testdata", "golden", name+".json")
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read golden file (run with -update to create it): %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("Output differs from %s (run with -update to accept):\n%s", path, got)
+	}
+}
+
+func TestStatsGolden(t *testing.T) {
+	tests := []struct {
+		name     string
+		platform string
+		tag      string
+		career   string
+		search   string
+	}{
+		{"pc-public", PlatformPC, "Viz-1213", "pc-public.html", "viz.json"},
+		{"pc-private", PlatformPC, "Hidden-4321", "pc-private.html", "hidden.json"},
+		{"pc-not-found", PlatformPC, "Nobody-0000", "not-found.html", "empty.json"},
+		{"pc-not-in-search", PlatformPC, "Viz-1213", "pc-public.html", "empty.json"},
+		{"pc-layout-changed", PlatformPC, "Viz-1213", "redesigned.html", "viz.json"},
+		{"psn-multiple-results", PlatformPSN, "TayuyaBreast", "psn-public.html", "tayuya.json"},
+		{"nintendo-switch", PlatformNS, "Mario-70af1a16ae4913bde139d46edb43df55", "nintendo-switch-public.html", "mario.json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := fixtureClient(t, tt.career, tt.search)
+			ps, err := c.StatsContext(context.Background(), tt.platform, tt.tag)
+			if err != nil {
+				assertGolden(t, tt.name, map[string]string{"error": err.Error()})
+				return
+			}
+			assertGolden(t, tt.name, ps)
+		})
+	}
+}
+
+func TestStatsInvalidPlatform(t *testing.T) {
+	if _, err := NewClient().Stats("gameboy", "Viz-1213"); err != ErrInvalidPlatform {
+		t.Errorf("Expected ErrInvalidPlatform, got %v", err)
+	}
+}
+
+func TestCaseInsensitiveTags(t *testing.T) {
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.EscapedPath())
+		switch {
+		case r.URL.Path == "/career/pc/Viz-1213":
+			http.ServeFile(w, r, filepath.Join("testdata", "career", "pc-public.html"))
+		case strings.HasPrefix(r.URL.Path, "/search/"):
+			http.ServeFile(w, r, filepath.Join("testdata", "search", "viz.json"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	opts := []Option{WithBaseURL(srv.URL + "/career"), WithAPIURL(srv.URL + "/search/")}
+
+	// Tags are case sensitive by default
+	if _, err := NewClient(opts...).PCStats("viz#1213"); err != ErrPlayerNotFound {
+		t.Errorf("Expected ErrPlayerNotFound, got %v", err)
+	}
+
+	requests = nil
+	ps, err := NewClient(append(opts, WithCaseInsensitiveTags())...).PCStats("viz#1213")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ps.Tag != "Viz-1213" || ps.ID != 1001 {
+		t.Errorf("Expected the canonical tag Viz-1213, got %q (account %d)", ps.Tag, ps.ID)
+	}
+	want := []string{"/career/pc/viz-1213", "/search/viz%231213", "/career/pc/Viz-1213"}
+	if strings.Join(requests, " ") != strings.Join(want, " ") {
+		t.Errorf("Expected requests %v, got %v", want, requests)
+	}
+
+	if _, err := NewClient(append(opts, WithCaseInsensitiveTags())...).PCStats("Nobody#1213"); err != ErrPlayerNotFound {
+		t.Errorf("Expected ErrPlayerNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,63 @@
+package ovrstat
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ProbeResult is the outcome of probing upstream with a known player, with
+// each step of a lookup reported separately
+type ProbeResult struct {
+	Career error        // Retrieving the career page
+	Parse  error        // Parsing the career page, nil if it wasn't retrieved
+	Report *ParseReport // The career pages parse report, if it was parsed
+	Search error        // Querying the search API for the player
+}
+
+// OK reports whether every step of the probe succeeded
+func (r ProbeResult) OK() bool {
+	return r.Career == nil && r.Parse == nil && r.Search == nil
+}
+
+// Probe checks that the career site and search API are reachable and that the
+// career page of a known player still parses, e.g. to verify a deployment is
+// ready to serve lookups. Probes bypass lookup coalescing but are subject to
+// the clients rate limiter and circuit breaker
+func (c *Client) Probe(ctx context.Context, platform, tag string) ProbeResult {
+	var r ProbeResult
+	pid, err := ParsePlayerID(platform, tag)
+	if err != nil {
+		r.Career, r.Search = err, err
+		return r
+	}
+
+	if res, err := c.get(ctx, EndpointCareer, c.baseURL+pid.careerPath()); err != nil {
+		r.Career = err
+	} else {
+		start := time.Now()
+		ps, err := c.ParseProfile(res.Body)
+		res.Body.Close()
+		c.parsed(ctx, time.Since(start), err)
+		r.Parse = err
+		if ps != nil {
+			r.Report = ps.Report
+		}
+	}
+
+	if res, err := c.get(ctx, EndpointSearch, c.apiURL+pid.searchName()); err != nil {
+		r.Search = err
+	} else {
+		platforms, err := ParseAccounts(res.Body)
+		res.Body.Close()
+		if err == nil && len(matchAccounts(platforms, func(p Platform) bool {
+			return p.Platform == platform && strings.EqualFold(accountTag(p), pid.URLName())
+		})) == 0 {
+			err = errors.Wrap(ErrPlayerNotFound, "Player missing from search results")
+		}
+		r.Search = err
+	}
+	return r
+}
@@ -0,0 +1,28 @@
+package ovrstat
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestProbe(t *testing.T) {
+	ctx := context.Background()
+	r := fixtureClient(t, "pc-public.html", "viz.json").Probe(ctx, PlatformPC, "Viz#1213")
+	if !r.OK() || r.Report == nil || !r.Report.OK() {
+		t.Errorf("Expected the probe to succeed, got %+v", r)
+	}
+
+	// A redesigned career page fails only the parse step
+	r = fixtureClient(t, "redesigned.html", "viz.json").Probe(ctx, PlatformPC, "Viz-1213")
+	if r.Career != nil || r.Search != nil || !errors.Is(r.Parse, ErrLayoutChanged) {
+		t.Errorf("Expected only the parse step to fail, got %+v", r)
+	}
+
+	// A player missing from search results fails the search step
+	r = fixtureClient(t, "pc-public.html", "empty.json").Probe(ctx, PlatformPC, "Viz-1213")
+	if r.Career != nil || r.Parse != nil || !errors.Is(r.Search, ErrPlayerNotFound) {
+		t.Errorf("Expected only the search step to fail, got %+v", r)
+	}
+}
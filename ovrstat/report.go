@@ -0,0 +1,80 @@
+package ovrstat
+
+import "github.com/PuerkitoBio/goquery"
+
+// SelectorCheck records whether an element the parser relies on was found
+type SelectorCheck struct {
+	Selector string `json:"selector"`
+	Required bool   `json:"required"`
+	Found    bool   `json:"found"`
+}
+
+// ParseReport is a sanity report of the elements found while parsing a career
+// page, useful for spotting partial breakage after a site redesign
+type ParseReport struct {
+	Checks []SelectorCheck `json:"checks"`
+}
+
+// selectorCheck is an element checked for while parsing a career page
+type selectorCheck struct {
+	selector string
+	required bool
+}
+
+var (
+	// mastheadChecks are checked for on every career page
+	mastheadChecks = []selectorCheck{
+		{"div.masthead", true},
+		{"img.player-portrait", true},
+		{"div.player-level div.u-vertical-center", true},
+		{"div.player-level", false},
+		{"div.player-rank", false},
+		{"div.EndorsementIcon-tooltip div.u-center", false},
+		{"div.EndorsementIcon", false},
+	}
+
+	// statsChecks are checked for on public career pages only
+	statsChecks = []selectorCheck{
+		{"div#quickplay", true},
+		{"div#competitive", false},
+	}
+)
+
+// check checks the passed document for each selector, appending the results
+// to the report
+func (r *ParseReport) check(doc *goquery.Document, checks []selectorCheck) {
+	for _, c := range checks {
+		r.Checks = append(r.Checks, SelectorCheck{
+			Selector: c.selector,
+			Required: c.required,
+			Found:    doc.Find(c.selector).Length() > 0,
+		})
+	}
+}
+
+// Missing returns the selectors of all required elements that weren't found
+func (r *ParseReport) Missing() []string {
+	var missing []string
+	for _, c := range r.Checks {
+		if c.Required && !c.Found {
+			missing = append(missing, c.Selector)
+		}
+	}
+	return missing
+}
+
+// Warnings returns the selectors of all optional elements that weren't found
+func (r *ParseReport) Warnings() []string {
+	var warnings []string
+	for _, c := range r.Checks {
+		if !c.Required && !c.Found {
+			warnings = append(warnings, c.Selector)
+		}
+	}
+	return warnings
+}
+
+// OK reports whether every required element was found
+func (r *ParseReport) OK() bool {
+	return len(r.Missing()) == 0
+}
@@ -0,0 +1,141 @@
+package ovrstat
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Resolution is the strategy used to choose an account when the search API
+// returns more than one account on the looked up platform. A lone account
+// matching the tag ignoring case is chosen under every strategy
+type Resolution string
+
+const (
+	// ResolveExact chooses the account whose URL name exactly matches the
+	// tag, or matches it ignoring case for clients created with
+	// WithCaseInsensitiveTags. This is the default
+	ResolveExact Resolution = "exact"
+
+	// ResolveCaseInsensitive chooses the account whose URL name matches the
+	// tag ignoring case, preferring an exact match
+	ResolveCaseInsensitive Resolution = "case-insensitive"
+
+	// ResolveByID chooses the account with the lowest ID, i.e. the oldest,
+	// among those whose URL name matches the tag ignoring case
+	ResolveByID Resolution = "by-id"
+
+	// ResolveFail never chooses between accounts
+	ResolveFail Resolution = "fail"
+)
+
+// Valid reports whether r is a known strategy
+func (r Resolution) Valid() bool {
+	switch r {
+	case ResolveExact, ResolveCaseInsensitive, ResolveByID, ResolveFail:
+		return true
+	}
+	return false
+}
+
+// WithResolution sets the strategy used to choose between accounts matching a
+// lookup. Lookups that can't be resolved fail with an AmbiguousError
+func WithResolution(r Resolution) Option {
+	return func(c *Client) { c.resolution = r }
+}
+
+// AmbiguousError is returned when more than one account matches a lookup and
+// the clients Resolution can't choose between them. It matches
+// ErrAmbiguousPlayer using errors.Is
+type AmbiguousError struct {
+	Platform   string
+	Tag        string
+	Resolution Resolution // The strategy that failed to choose an account
+	Candidates []Platform // The matching accounts, ordered by ID
+}
+
+// Error returns a description of the ambiguous lookup
+func (e *AmbiguousError) Error() string {
+	return fmt.Sprintf("Ambiguous player: %d %s accounts match %q",
+		len(e.Candidates), e.Platform, e.Tag)
+}
+
+// Is reports whether target is ErrAmbiguousPlayer
+func (e *AmbiguousError) Is(target error) bool {
+	return target == ErrAmbiguousPlayer
+}
+
+// resolveAccount chooses the account a lookup resolves to. Only accounts on
+// the platform whose tag matches the looked up tag ignoring case are
+// candidates, so the chosen account never belongs to a different player. A
+// non-zero id chooses that candidate regardless of the clients Resolution
+func (c *Client) resolveAccount(platform, tag string, id int, accounts []Platform) (Platform, error) {
+	// Order the candidates so resolution never depends on the order of the
+	// search results
+	candidates := matchAccounts(accounts, func(p Platform) bool {
+		return p.Platform == platform && strings.EqualFold(accountTag(p), tag)
+	})
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].ID < candidates[j].ID })
+	if len(candidates) == 0 {
+		return Platform{}, ErrPlayerNotFound
+	}
+	if id != 0 {
+		for _, p := range candidates {
+			if p.ID == id {
+				return p, nil
+			}
+		}
+		return Platform{}, ErrPlayerNotFound
+	}
+	if len(candidates) == 1 {
+		return candidates[0], nil
+	}
+
+	var match []Platform
+	switch c.resolution {
+	case ResolveExact, ResolveCaseInsensitive:
+		match = matchAccounts(candidates, func(p Platform) bool { return accountTag(p) == tag })
+		if len(match) == 0 && (c.resolution == ResolveCaseInsensitive || c.caseInsensitive) {
+			match = candidates
+		}
+	case ResolveByID:
+		match = candidates[:1]
+	case ResolveFail:
+		match = candidates
+	}
+	if len(match) == 1 {
+		return match[0], nil
+	}
+	return Platform{}, &AmbiguousError{
+		Platform:   platform,
+		Tag:        tag,
+		Resolution: c.resolution,
+		Candidates: candidates,
+	}
+}
+
+// matchAccounts returns the accounts satisfying match
+func matchAccounts(platforms []Platform, match func(Platform) bool) []Platform {
+	var out []Platform
+	for _, p := range platforms {
+		if match(p) {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// accountTag returns the tag of an account as used in its career URL
+func accountTag(p Platform) string {
+	if tag, err := url.PathUnescape(p.URLName); err == nil {
+		return tag
+	}
+	return p.URLName
+}
+
+// prestige returns the prestige of an account from its player level
+func prestige(p Platform) int {
+	return int(math.Floor(float64(p.PlayerLevel) / 100))
+}
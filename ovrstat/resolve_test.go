@@ -0,0 +1,133 @@
+package ovrstat
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestResolveAccount(t *testing.T) {
+	accounts := []Platform{
+		{Platform: PlatformPSN, ID: 3003, Name: "Tayuya", URLName: "Tayuya", PlayerLevel: 40},
+		{Platform: PlatformPSN, ID: 3001, Name: "tayuya", URLName: "tayuya", PlayerLevel: 120},
+		{Platform: PlatformPSN, ID: 3002, Name: "TAYUYA", URLName: "TAYUYA", PlayerLevel: 688},
+		{Platform: PlatformXBL, ID: 3000, Name: "Tayuya", URLName: "Tayuya", PlayerLevel: 12},
+		{Platform: PlatformPSN, ID: 2999, Name: "Tayuya2", URLName: "Tayuya2", PlayerLevel: 12},
+		{Platform: PlatformPSN, ID: 2998, Name: "Genji", URLName: "Genji", PlayerLevel: 5},
+	}
+	tests := []struct {
+		resolution Resolution
+		tag        string
+		id         int
+		want       int // The resolved ID, zero if ambiguous
+	}{
+		{ResolveExact, "Tayuya", 0, 3003},
+		{ResolveExact, "tAyUyA", 0, 0},
+		{ResolveCaseInsensitive, "TAYUYA", 0, 3002},
+		{ResolveCaseInsensitive, "tAyUyA", 0, 0},
+		{ResolveByID, "tAyUyA", 0, 3001},
+		{ResolveFail, "Tayuya", 0, 0},
+		{ResolveFail, "Tayuya", 3002, 3002},
+		{ResolveFail, "Tayuya2", 0, 2999},
+		{ResolveExact, "genji", 0, 2998},
+		{ResolveCaseInsensitive, "GENJI", 0, 2998},
+		{ResolveByID, "gEnJi", 0, 2998},
+		{ResolveFail, "genji", 0, 2998},
+	}
+	for _, tt := range tests {
+		c := NewClient(WithResolution(tt.resolution))
+		var ps PlayerStats
+		err := c.resolveStats(&ps, PlatformPSN, tt.tag, tt.id, accounts)
+		if tt.want == 0 {
+			var ae *AmbiguousError
+			if !errors.As(err, &ae) || !errors.Is(err, ErrAmbiguousPlayer) {
+				t.Errorf("%s %q: expected an AmbiguousError, got %v", tt.resolution, tt.tag, err)
+				continue
+			}
+			if len(ae.Candidates) != 3 || ae.Candidates[0].ID != 3001 || ae.Resolution != tt.resolution {
+				t.Errorf("%s %q: expected 3 psn candidates ordered by ID, got %+v", tt.resolution, tt.tag, ae)
+			}
+			continue
+		}
+		if err != nil || ps.ID != tt.want {
+			t.Errorf("%s %q: expected account %d, got %d, %v", tt.resolution, tt.tag, tt.want, ps.ID, err)
+		}
+	}
+
+	// An ID that doesn't match the platform and tag isn't found, nor is a tag
+	// only other accounts contain
+	for _, tt := range []struct {
+		tag string
+		id  int
+	}{{"Tayuya", 3000}, {"Tayuya", 2999}, {"Tay", 0}} {
+		var ps PlayerStats
+		if err := NewClient().resolveStats(&ps, PlatformPSN, tt.tag, tt.id, accounts); err != ErrPlayerNotFound {
+			t.Errorf("%q %d: expected ErrPlayerNotFound, got %v", tt.tag, tt.id, err)
+		}
+	}
+}
+
+// caseClient returns a client serving two psn players whose tags differ only
+// in case, each with their own career page
+func caseClient(t *testing.T, opts ...Option) *Client {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/career/psn/TayuyaBreast":
+			http.ServeFile(w, r, filepath.Join("testdata", "career", "psn-public.html"))
+		case "/career/psn/tayuyabreast":
+			http.ServeFile(w, r, filepath.Join("testdata", "career", "pc-private.html"))
+		case "/search/TayuyaBreast", "/search/tayuyabreast":
+			http.ServeFile(w, r, filepath.Join("testdata", "search", "tayuya-case.json"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return NewClient(append([]Option{WithBaseURL(srv.URL + "/career"), WithAPIURL(srv.URL + "/search/")}, opts...)...)
+}
+
+func TestStatsByID(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		resolution Resolution
+		tag        string
+		id         int
+		want       int
+		wantTag    string
+	}{
+		{ResolveExact, "TayuyaBreast", 0, 3002, "TayuyaBreast"},
+		{ResolveExact, "TayuyaBreast", 3004, 3004, "tayuyabreast"},
+		{ResolveByID, "tayuyabreast", 0, 3002, "TayuyaBreast"},
+		{ResolveFail, "tayuyabreast", 3002, 3002, "TayuyaBreast"},
+	}
+	for _, tt := range tests {
+		ps, err := caseClient(t, WithResolution(tt.resolution)).StatsByID(ctx, PlatformPSN, tt.tag, tt.id)
+		if err != nil {
+			t.Errorf("%s %q %d: %v", tt.resolution, tt.tag, tt.id, err)
+			continue
+		}
+		if ps.ID != tt.want || ps.Tag != tt.wantTag {
+			t.Errorf("%s %q %d: expected account %d %q, got %d %q", tt.resolution, tt.tag, tt.id, tt.want, tt.wantTag, ps.ID, ps.Tag)
+		}
+		// The career page and the account must belong to the same player,
+		// whose level the account reports including prestige
+		if level := map[int]int{3002: 688, 3004: 317}[ps.ID]; ps.Prestige*100+ps.Level != level {
+			t.Errorf("%s %q %d: career level %d prestige %d disagrees with account level %d",
+				tt.resolution, tt.tag, tt.id, ps.Level, ps.Prestige, level)
+		}
+	}
+
+	// Accounts with a different tag can't be chosen by ID
+	if _, err := caseClient(t).StatsByID(ctx, PlatformPSN, "TayuyaBreast", 3001); err != ErrPlayerNotFound {
+		t.Errorf("Expected ErrPlayerNotFound, got %v", err)
+	}
+	var ae *AmbiguousError
+	if _, err := caseClient(t, WithResolution(ResolveFail)).StatsContext(ctx, PlatformPSN, "TayuyaBreast"); !errors.As(err, &ae) || len(ae.Candidates) != 2 {
+		t.Errorf("Expected an AmbiguousError with 2 candidates, got %v", err)
+	}
+}
@@ -0,0 +1,114 @@
+package ovrstat
+
+import (
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RetryPolicy configures how failed upstream requests are retried. Requests
+// are retried on transport errors and on any of the RetryableStatus codes,
+// waiting an exponentially increasing, jittered delay between attempts. A
+// Retry-After header sent by upstream is honored as long as it doesn't exceed
+// MaxDelay
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts made, including the first
+	MaxAttempts int
+
+	// BaseDelay is the delay before the first retry, doubling every attempt
+	// up to MaxDelay
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+
+	// Jitter is the fraction of each delay that is randomized, from 0 to 1
+	Jitter float64
+
+	// RetryableStatus lists the upstream response codes that are retried
+	RetryableStatus []int
+}
+
+// DefaultRetryPolicy is the RetryPolicy used by clients unless configured
+// otherwise
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   250 * time.Millisecond,
+	MaxDelay:    5 * time.Second,
+	Jitter:      0.5,
+	RetryableStatus: []int{
+		http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout,
+	},
+}
+
+// NoRetry is a RetryPolicy that never retries
+var NoRetry = RetryPolicy{MaxAttempts: 1}
+
+// WithRetryPolicy sets the policy used to retry failed upstream requests
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(c *Client) { c.retry = p }
+}
+
+var (
+	jitterMu  sync.Mutex
+	jitterRnd = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+// delay returns how long to wait before retrying a request that failed on
+// the passed attempt, or false if it shouldn't be retried
+func (p RetryPolicy) delay(attempt int, err *UpstreamError) (time.Duration, bool) {
+	if attempt >= p.MaxAttempts || !p.retryable(err) {
+		return 0, false
+	}
+
+	// Honor the upstreams requested delay if it's within reason
+	if err.RetryAfter > 0 {
+		if err.RetryAfter > p.MaxDelay {
+			return 0, false
+		}
+		return err.RetryAfter, true
+	}
+
+	d := float64(p.BaseDelay) * math.Pow(2, float64(attempt-1))
+	if d > float64(p.MaxDelay) {
+		d = float64(p.MaxDelay)
+	}
+	if p.Jitter > 0 {
+		jitterMu.Lock()
+		d -= d * p.Jitter * jitterRnd.Float64()
+		jitterMu.Unlock()
+	}
+	return time.Duration(d), true
+}
+
+// retryable reports whether the failed request may be retried
+func (p RetryPolicy) retryable(err *UpstreamError) bool {
+	if err.StatusCode == 0 {
+		return true
+	}
+	for _, s := range p.RetryableStatus {
+		if s == err.StatusCode {
+			return true
+		}
+	}
+	return false
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as a date
+func parseRetryAfter(v string, now time.Time) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
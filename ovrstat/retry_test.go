@@ -0,0 +1,87 @@
+package ovrstat
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var testRetryPolicy = RetryPolicy{
+	MaxAttempts:     3,
+	BaseDelay:       time.Millisecond,
+	MaxDelay:        10 * time.Millisecond,
+	Jitter:          0.5,
+	RetryableStatus: []int{http.StatusServiceUnavailable},
+}
+
+// flakyServer serves the pc-public fixtures after failing the first failures
+// requests to each endpoint with a 503
+func flakyServer(t *testing.T, failures int32) (*httptest.Server, *int32) {
+	t.Helper()
+	var careerHits, searchHits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits, file := &careerHits, filepath.Join("testdata", "career", "pc-public.html")
+		if strings.HasPrefix(r.URL.Path, "/search/") {
+			hits, file = &searchHits, filepath.Join("testdata", "search", "viz.json")
+		}
+		if atomic.AddInt32(hits, 1) <= failures {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		http.ServeFile(w, r, file)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &careerHits
+}
+
+func TestRetryRecovers(t *testing.T) {
+	srv, careerHits := flakyServer(t, 2)
+	c := NewClient(WithBaseURL(srv.URL+"/career"), WithAPIURL(srv.URL+"/search/"),
+		WithRetryPolicy(testRetryPolicy))
+
+	ps, err := c.PCStats("Viz-1213")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ps.Name != "Viz#1213" {
+		t.Errorf("Expected name Viz#1213, got %q", ps.Name)
+	}
+	if *careerHits != 3 {
+		t.Errorf("Expected 3 career page attempts, got %d", *careerHits)
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	srv, careerHits := flakyServer(t, 5)
+	c := NewClient(WithBaseURL(srv.URL+"/career"), WithAPIURL(srv.URL+"/search/"),
+		WithRetryPolicy(testRetryPolicy))
+
+	_, err := c.PCStats("Viz-1213")
+	var ue *UpstreamError
+	if !errors.As(err, &ue) || ue.Attempts != 3 {
+		t.Fatalf("Expected an UpstreamError after 3 attempts, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "after 3 attempts") {
+		t.Errorf("Expected attempts in error message, got %q", err)
+	}
+	if *careerHits != 3 {
+		t.Errorf("Expected 3 career page attempts, got %d", *careerHits)
+	}
+}
+
+func TestRetryAfterTooLong(t *testing.T) {
+	p := testRetryPolicy
+	if _, retry := p.delay(1, &UpstreamError{StatusCode: http.StatusServiceUnavailable, RetryAfter: time.Minute}); retry {
+		t.Error("Expected a Retry-After beyond MaxDelay not to be retried")
+	}
+	if _, retry := p.delay(1, &UpstreamError{StatusCode: http.StatusForbidden}); retry {
+		t.Error("Expected a non-retryable status not to be retried")
+	}
+}
@@ -0,0 +1,37 @@
+package ovrstat
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Search returns every account matching name on any platform using the
+// DefaultClient
+func Search(ctx context.Context, name string) ([]Platform, error) {
+	return DefaultClient.Search(ctx, name)
+}
+
+// Search returns every account matching name on any platform, in the order
+// returned by the account-by-name API. Name is either a players name, matching
+// all of its BattleTags, or a full BattleTag such as Viz#1213
+func (c *Client) Search(ctx context.Context, name string) ([]Platform, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, ErrInvalidSearch
+	}
+
+	res, err := c.get(ctx, EndpointSearch, c.apiURL+url.PathEscape(name))
+	if err != nil {
+		var ue *UpstreamError
+		if errors.As(err, &ue) && ue.StatusCode == http.StatusNotFound {
+			return []Platform{}, nil
+		}
+		return nil, errors.Wrap(err, "Failed to perform platform API request")
+	}
+	defer res.Body.Close()
+	return ParseAccounts(res.Body)
+}
@@ -0,0 +1,34 @@
+package ovrstat
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestSearch(t *testing.T) {
+	ctx := context.Background()
+	accounts, err := fixtureClient(t, "", "tayuya.json").Search(ctx, "TayuyaBreast")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Accounts on every platform are returned
+	platforms := make(map[string]int)
+	for _, a := range accounts {
+		platforms[a.Platform]++
+	}
+	if len(accounts) != 3 || platforms[PlatformPSN] != 2 || platforms[PlatformXBL] != 1 {
+		t.Errorf("Expected accounts on psn and xbl, got %+v", accounts)
+	}
+
+	accounts, err = fixtureClient(t, "", "empty.json").Search(ctx, "Nobody")
+	if err != nil || len(accounts) != 0 {
+		t.Errorf("Expected no accounts, got %+v, %v", accounts, err)
+	}
+
+	if _, err := fixtureClient(t, "", "empty.json").Search(ctx, " "); !errors.Is(err, ErrInvalidSearch) {
+		t.Errorf("Expected ErrInvalidSearch, got %v", err)
+	}
+}
@@ -0,0 +1,219 @@
+package ovrstat
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:generate go run gen_catalog.go -o catalog.go testdata/career
+
+// Career stat categories as they appear in the CareerStats JSON
+const (
+	CategoryAssists       = "assists"
+	CategoryAverage       = "average"
+	CategoryBest          = "best"
+	CategoryCombat        = "combat"
+	CategoryDeaths        = "deaths"
+	CategoryHeroSpecific  = "heroSpecific"
+	CategoryGame          = "game"
+	CategoryMatchAwards   = "matchAwards"
+	CategoryMiscellaneous = "miscellaneous"
+)
+
+// Unit describes how a stats value is measured
+type Unit string
+
+const (
+	// UnitCount is a plain number such as eliminations or damage done
+	UnitCount Unit = "count"
+
+	// UnitPercent is a percentage between 0 and 100
+	UnitPercent Unit = "percent"
+
+	// UnitSeconds is a time value, Value holds its number of seconds
+	UnitSeconds Unit = "seconds"
+
+	// UnitText is a value that couldn't be parsed as a number, only Raw is set
+	UnitText Unit = "text"
+)
+
+// Stat is a single typed career stat
+type Stat struct {
+	Key      string  `json:"key"`
+	Category string  `json:"category"`
+	Value    float64 `json:"value"`
+	Unit     Unit    `json:"unit"`
+	Raw      string  `json:"raw"`
+}
+
+// newStat creates a typed Stat from the raw value scraped from the career page,
+// using the catalog to determine its unit when the key is known
+func newStat(category, key, raw string) Stat {
+	s := Stat{Key: key, Category: category, Raw: raw}
+
+	unit, known := LookupStat(category, key)
+	if unit == UnitSeconds || (!known && isDuration(raw)) {
+		d, err := ParseDuration(raw)
+		if err != nil {
+			s.Unit = UnitText
+			return s
+		}
+		s.Unit = UnitSeconds
+		s.Value = d.Seconds()
+		return s
+	}
+
+	val := strings.TrimSpace(strings.Replace(raw, ",", "", -1))
+	if strings.HasSuffix(val, "%") {
+		val = strings.TrimSuffix(val, "%")
+		if !known {
+			unit = UnitPercent
+		}
+	}
+
+	if unit == "" {
+		unit = UnitCount
+	}
+
+	f, err := strconv.ParseFloat(val, 64)
+	if err != nil {
+		s.Unit = UnitText
+		return s
+	}
+	s.Unit = unit
+	s.Value = f
+	return s
+}
+
+// Duration returns the value of a UnitSeconds stat as a time.Duration
+func (s Stat) Duration() time.Duration {
+	if s.Unit != UnitSeconds {
+		return 0
+	}
+	return time.Duration(s.Value * float64(time.Second))
+}
+
+// Stat returns the stat with the passed category and key
+func (cs *CareerStats) Stat(category, key string) (Stat, bool) {
+	for _, s := range cs.Stats {
+		if s.Category == category && s.Key == key {
+			return s, true
+		}
+	}
+	return Stat{}, false
+}
+
+// addLegacy sets a stat in the untyped category maps
+func (cs *CareerStats) addLegacy(category, key, raw string) {
+	var m *map[string]interface{}
+	switch category {
+	case CategoryAssists:
+		m = &cs.Assists
+	case CategoryAverage:
+		m = &cs.Average
+	case CategoryBest:
+		m = &cs.Best
+	case CategoryCombat:
+		m = &cs.Combat
+	case CategoryDeaths:
+		m = &cs.Deaths
+	case CategoryHeroSpecific:
+		m = &cs.HeroSpecific
+	case CategoryGame:
+		m = &cs.Game
+	case CategoryMatchAwards:
+		m = &cs.MatchAwards
+	case CategoryMiscellaneous:
+		m = &cs.Miscellaneous
+	default:
+		return
+	}
+
+	// Creates category stat maps if they don't exist, missing categories are
+	// encoded as null
+	if *m == nil {
+		*m = make(map[string]interface{})
+	}
+	(*m)[key] = parseType(raw)
+}
+
+// Legacy returns the stats with the untyped category maps populated from
+// Stats, encoded in the original JSON shape
+func (cs *CareerStats) Legacy() *CareerStats {
+	if cs.legacy {
+		return cs
+	}
+	l := &CareerStats{Stats: cs.Stats, legacy: true}
+	for _, s := range cs.Stats {
+		l.addLegacy(s.Category, s.Key, strings.Replace(s.Raw, ",", "", -1))
+	}
+	return l
+}
+
+// legacyCareerStats is the original JSON shape of CareerStats
+type legacyCareerStats struct {
+	Assists       map[string]interface{} `json:"assists"`
+	Average       map[string]interface{} `json:"average"`
+	Best          map[string]interface{} `json:"best"`
+	Combat        map[string]interface{} `json:"combat"`
+	Deaths        map[string]interface{} `json:"deaths"`
+	HeroSpecific  map[string]interface{} `json:"heroSpecific"`
+	Game          map[string]interface{} `json:"game"`
+	MatchAwards   map[string]interface{} `json:"matchAwards"`
+	Miscellaneous map[string]interface{} `json:"miscellaneous"`
+}
+
+// MarshalJSON encodes the stats in the original JSON shape when parsed with
+// WithLegacyCareerStats or returned by Legacy, and as the typed Stats
+// otherwise
+func (cs CareerStats) MarshalJSON() ([]byte, error) {
+	if cs.legacy {
+		return json.Marshal(legacyCareerStats{
+			Assists:       cs.Assists,
+			Average:       cs.Average,
+			Best:          cs.Best,
+			Combat:        cs.Combat,
+			Deaths:        cs.Deaths,
+			HeroSpecific:  cs.HeroSpecific,
+			Game:          cs.Game,
+			MatchAwards:   cs.MatchAwards,
+			Miscellaneous: cs.Miscellaneous,
+		})
+	}
+	stats := cs.Stats
+	if stats == nil {
+		stats = []Stat{}
+	}
+	return json.Marshal(struct {
+		Stats []Stat `json:"stats"`
+	}{stats})
+}
+
+// UnmarshalJSON decodes either JSON shape, keeping the shape it was encoded in
+func (cs *CareerStats) UnmarshalJSON(b []byte) error {
+	var v struct {
+		Stats json.RawMessage `json:"stats"`
+		legacyCareerStats
+	}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	*cs = CareerStats{
+		Assists:       v.Assists,
+		Average:       v.Average,
+		Best:          v.Best,
+		Combat:        v.Combat,
+		Deaths:        v.Deaths,
+		HeroSpecific:  v.HeroSpecific,
+		Game:          v.Game,
+		MatchAwards:   v.MatchAwards,
+		Miscellaneous: v.Miscellaneous,
+		legacy:        v.Stats == nil,
+	}
+	if v.Stats != nil {
+		return json.Unmarshal(v.Stats, &cs.Stats)
+	}
+	return nil
+}
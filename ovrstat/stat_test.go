@@ -0,0 +1,168 @@
+package ovrstat
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNewStat(t *testing.T) {
+	tests := []struct {
+		category, key, raw string
+		unit               Unit
+		value              float64
+	}{
+		{CategoryCombat, "damageDone", "1,234,567", UnitCount, 1234567},
+		{CategoryCombat, "weaponAccuracy", "41%", UnitPercent, 41},
+		{CategoryGame, "winPercentage", "54", UnitPercent, 54},
+		{CategoryAverage, "eliminationsPerLife", "4.21", UnitCount, 4.21},
+		{CategoryCombat, "objectiveTime", "12:34:56", UnitSeconds, 45296},
+		{CategoryCombat, "objectiveTime", "--", UnitSeconds, 0},
+		{CategoryCombat, "objectiveTime", "soon", UnitText, 0},
+		{CategoryCombat, "damageDone", "lots", UnitText, 0},
+
+		// Keys missing from the catalog are typed from their value
+		{CategoryHeroSpecific, "soundBarriersProvided", "1,024", UnitCount, 1024},
+		{CategoryHeroSpecific, "scopedAccuracy", "62%", UnitPercent, 62},
+		{CategoryHeroSpecific, "nanoBoostedTime", "2 hours", UnitSeconds, 7200},
+		{CategoryHeroSpecific, "selfHealing", "n/a", UnitText, 0},
+	}
+	for _, tt := range tests {
+		s := newStat(tt.category, tt.key, tt.raw)
+		want := Stat{Key: tt.key, Category: tt.category, Value: tt.value, Unit: tt.unit, Raw: tt.raw}
+		if s != want {
+			t.Errorf("newStat(%q, %q, %q) = %+v, want %+v", tt.category, tt.key, tt.raw, s, want)
+		}
+	}
+}
+
+func TestAddLegacy(t *testing.T) {
+	var cs CareerStats
+	cs.addLegacy(CategoryCombat, "damageDone", "1234567")
+	cs.addLegacy(CategoryCombat, "objectiveTime", "12:34:56")
+	cs.addLegacy(CategoryAverage, "eliminationsPerLife", "4.21")
+	cs.addLegacy("unknownCategory", "ignored", "1")
+
+	want := map[string]interface{}{"damageDone": 1234567, "objectiveTime": "12:34:56"}
+	if !reflect.DeepEqual(cs.Combat, want) {
+		t.Errorf("Expected combat %v, got %v", want, cs.Combat)
+	}
+	if v := cs.Average["eliminationsPerLife"]; v != 4.21 {
+		t.Errorf("Expected a float average, got %#v", v)
+	}
+	if cs.Assists != nil || cs.Miscellaneous != nil {
+		t.Errorf("Expected categories without stats to stay nil, got %+v", cs)
+	}
+}
+
+func TestCareerStatsJSON(t *testing.T) {
+	cs := &CareerStats{Stats: []Stat{
+		newStat(CategoryCombat, "damageDone", "1,234"),
+		newStat(CategoryGame, "timePlayed", "1 hour"),
+	}}
+
+	// Typed stats are encoded alone
+	b, _ := json.Marshal(cs)
+	if string(b) != `{"stats":[{"key":"damageDone","category":"combat","value":1234,"unit":"count","raw":"1,234"},`+
+		`{"key":"timePlayed","category":"game","value":3600,"unit":"seconds","raw":"1 hour"}]}` {
+		t.Errorf("Unexpected typed JSON %s", b)
+	}
+	var typed CareerStats
+	if err := json.Unmarshal(b, &typed); err != nil || !reflect.DeepEqual(typed.Stats, cs.Stats) || typed.legacy {
+		t.Errorf("Expected the typed stats to round trip, got %+v, %v", typed, err)
+	}
+
+	// The legacy shape lists every category, null when empty, and no stats
+	b, _ = json.Marshal(cs.Legacy())
+	want := `{"assists":null,"average":null,"best":null,"combat":{"damageDone":1234},"deaths":null,` +
+		`"heroSpecific":null,"game":{"timePlayed":"1 hour"},"matchAwards":null,"miscellaneous":null}`
+	if string(b) != want {
+		t.Errorf("Expected legacy JSON %s, got %s", want, b)
+	}
+	var legacy CareerStats
+	if err := json.Unmarshal(b, &legacy); err != nil || !legacy.legacy || legacy.Stats != nil {
+		t.Fatalf("Expected the legacy shape to round trip, got %+v, %v", legacy, err)
+	}
+	if b2, _ := json.Marshal(legacy); string(b2) != want {
+		t.Errorf("Expected legacy JSON to be encoded unchanged, got %s", b2)
+	}
+}
+
+func TestCatalog(t *testing.T) {
+	categories := map[string]bool{
+		CategoryAssists: true, CategoryAverage: true, CategoryBest: true, CategoryCombat: true,
+		CategoryDeaths: true, CategoryGame: true, CategoryMatchAwards: true, CategoryMiscellaneous: true,
+	}
+	for category, keys := range Catalog {
+		if !categories[category] {
+			t.Errorf("Unexpected catalog category %q", category)
+		}
+		for key, unit := range keys {
+			switch unit {
+			case UnitCount, UnitPercent, UnitSeconds:
+			default:
+				t.Errorf("%s.%s: unexpected unit %q", category, key, unit)
+			}
+		}
+	}
+
+	if u, ok := LookupStat(CategoryGame, "timePlayed"); !ok || u != UnitSeconds {
+		t.Errorf("Expected timePlayed in seconds, got %q %v", u, ok)
+	}
+	if u, ok := LookupStat(CategoryCombat, "weaponAccuracy"); !ok || u != UnitPercent {
+		t.Errorf("Expected weaponAccuracy as a percentage, got %q %v", u, ok)
+	}
+	for _, tt := range [][2]string{{CategoryHeroSpecific, "selfHealing"}, {CategoryGame, "unknown"}, {"unknown", "timePlayed"}} {
+		if u, ok := LookupStat(tt[0], tt[1]); ok || u != "" {
+			t.Errorf("Expected %s.%s to be unknown, got %q %v", tt[0], tt[1], u, ok)
+		}
+	}
+}
+
+// TestCatalogGenerated fails when catalog.go differs from the output of its
+// go:generate directive, run with the output redirected to a temp file
+func TestCatalogGenerated(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping running the catalog generator in short mode")
+	}
+	src, err := os.ReadFile("stat.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var args []string
+	for _, line := range strings.Split(string(src), "\n") {
+		if strings.HasPrefix(line, "//go:generate ") {
+			args = strings.Fields(strings.TrimPrefix(line, "//go:generate "))
+		}
+	}
+	out := filepath.Join(t.TempDir(), "catalog.go")
+	for i, arg := range args {
+		if arg == "-o" && i+1 < len(args) {
+			args[i+1] = out
+		}
+	}
+	if len(args) == 0 || args[0] != "go" {
+		t.Fatalf("Expected a go:generate directive running go, got %q", args)
+	}
+
+	cmd := exec.Command(args[0], args[1:]...)
+	if b, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("Failed to run %q: %v\n%s", args, err, b)
+	}
+	got, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := os.ReadFile("catalog.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Error("catalog.go differs from the generator output, run make generate")
+	}
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/s32x/ovrstat/internal/pool"
+	"github.com/s32x/ovrstat/ovrstat"
+)
+
+// batchRequest is the body of a batch stats request
+type batchRequest struct {
+	Players []ovrstat.BatchLookup `json:"players"`
+}
+
+// batchResponse holds a result for every player of a batch, in the order
+// requested
+type batchResponse[T any] struct {
+	Results []batchResult[T] `json:"results"`
+}
+
+// batchResult is the outcome of a single lookup in a batch, holding either
+// the players stats or the error it failed with along with its status
+type batchResult[T any] struct {
+	ovrstat.BatchLookup
+	Status int       `json:"status"`
+	Stats  *T        `json:"stats,omitempty"`
+	Error  *apiError `json:"error,omitempty"`
+}
+
+// batch handles looking up several players in a single request, serving the
+// v1 schema
+func (h *handler) batch(c echo.Context) error {
+	return serveBatch(h, c, newPlayerStatsV1)
+}
+
+// batchV2 handles looking up several players in a single request, serving the
+// v2 schema
+func (h *handler) batchV2(c echo.Context) error {
+	return serveBatch(h, c, newPlayerStatsV2)
+}
+
+// serveBatch looks up every player of a batch request concurrently and serves
+// their stats in the schema returned by render. Failed lookups are reported in
+// their own result and never fail the batch as a whole
+func serveBatch[T any](h *handler, c echo.Context, render func(*ovrstat.PlayerStats) *T) error {
+	var req batchRequest
+	if err := c.Bind(&req); err != nil {
+		return newErr(http.StatusBadRequest, codeBadRequest, "Invalid batch request body")
+	}
+	if len(req.Players) == 0 || len(req.Players) > h.batchCfg.MaxPlayers {
+		return newErr(http.StatusBadRequest, codeBadRequest,
+			"A batch must list between 1 and "+strconv.Itoa(h.batchCfg.MaxPlayers)+" players")
+	}
+	// Every player costs a request of the callers rate limit
+	if err := h.rateLimit.limit(c, len(req.Players)); err != nil {
+		return err
+	}
+
+	res := batchResponse[T]{Results: make([]batchResult[T], len(req.Players))}
+	h.lookupBatch(c.Request().Context(), req.Players, func(i int, stats *ovrstat.PlayerStats, err error) {
+		r := batchResult[T]{BatchLookup: req.Players[i], Status: http.StatusOK}
+		if err != nil {
+			r.Error = h.apiErr(err)
+			r.Status = r.Error.Status
+		} else {
+			r.Stats = render(stats)
+		}
+		res.Results[i] = r
+	})
+	return c.JSON(http.StatusOK, res)
+}
+
+// lookupBatch looks up the passed players using at most the configured number
+// of workers, calling done with the index and outcome of every lookup
+func (h *handler) lookupBatch(ctx context.Context, players []ovrstat.BatchLookup,
+	done func(i int, stats *ovrstat.PlayerStats, err error)) {
+	pool.Each(len(players), h.batchCfg.Workers, func(i int) {
+		pid, err := ovrstat.ParsePlayerID(players[i].Platform, players[i].Tag)
+		if err != nil {
+			done(i, nil, err)
+			return
+		}
+		stats, err := h.lookup(ctx, nil, pid, 0)
+		done(i, stats, err)
+	})
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBatch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/career/pc/Viz-1213":
+			http.ServeFile(w, r, filepath.Join("..", "ovrstat", "testdata", "career", "pc-public.html"))
+		case strings.HasPrefix(r.URL.Path, "/search/"):
+			http.ServeFile(w, r, filepath.Join("..", "ovrstat", "testdata", "search", "viz.json"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	cfg := DefaultConfig()
+	cfg.Upstream.BaseURL = srv.URL + "/career"
+	cfg.Upstream.APIURL = srv.URL + "/search/"
+	cfg.Log.Level = slog.LevelError
+	cfg.Batch.MaxPlayers = 4
+	e, _ := newService(cfg)
+	post := func(target, body string, v interface{}) int {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		e.ServeHTTP(rec, req)
+		json.Unmarshal(rec.Body.Bytes(), v)
+		return rec.Code
+	}
+
+	var res batchResponse[playerStatsV2]
+	code := post("/v2/stats/batch", `{"players": [
+		{"platform": "pc", "tag": "Viz-1213"},
+		{"platform": "pc", "tag": "Nobody-0000"},
+		{"platform": "pc", "tag": "Viz"},
+		{"platform": "wii", "tag": "Mario"}
+	]}`, &res)
+	if code != http.StatusOK || len(res.Results) != 4 {
+		t.Fatalf("Expected 4 results, got %d %+v", code, res)
+	}
+	want := []struct {
+		status int
+		code   string
+	}{
+		{http.StatusOK, ""},
+		{http.StatusNotFound, codePlayerNotFound},
+		{http.StatusBadRequest, codeInvalidTag},
+		{http.StatusBadRequest, codeInvalidPlatform},
+	}
+	for i, r := range res.Results {
+		if r.Status != want[i].status || (r.Error == nil) != (want[i].code == "") ||
+			(r.Error != nil && r.Error.Code != want[i].code) {
+			t.Errorf("Result %d: expected %d %s, got %+v", i, want[i].status, want[i].code, r)
+		}
+	}
+	if s := res.Results[0].Stats; s == nil || s.Name != "Viz#1213" || res.Results[0].Tag != "Viz-1213" {
+		t.Errorf("Expected the stats of Viz-1213 first, got %+v", res.Results[0])
+	}
+
+	var v1 batchResponse[map[string]interface{}]
+	if code := post("/stats/batch", `{"players": [{"platform": "pc", "tag": "Viz#1213"}]}`, &v1); code != http.StatusOK ||
+		len(v1.Results) != 1 || (*v1.Results[0].Stats)["quickPlayStats"] == nil {
+		t.Errorf("Expected v1 stats, got %d %+v", code, v1)
+	}
+
+	for _, body := range []string{`{"players": []}`, `{"players": [{}, {}, {}, {}, {}]}`, `[`} {
+		var ae apiError
+		if code := post("/v2/stats/batch", body, &ae); code != http.StatusBadRequest || ae.Code != codeBadRequest {
+			t.Errorf("Expected %s to be rejected, got %d %+v", body, code, ae)
+		}
+	}
+}
+
+func TestBatchRateLimit(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Log.Level = slog.LevelError
+	cfg.Auth.Keys = []APIKey{{Key: "secret", RequestsPerMinute: 30}, {Key: "small", RequestsPerMinute: 10}}
+	e, _ := newService(cfg)
+	post := func(key string, players int) *httptest.ResponseRecorder {
+		// Invalid tags fail without an upstream request but still cost one
+		body := `{"players": [` + strings.Repeat(`{"platform": "pc", "tag": "x"},`, players-1) +
+			`{"platform": "pc", "tag": "x"}]}`
+		req := httptest.NewRequest(http.MethodPost, "/v2/stats/batch", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(apiKeyHeader, key)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := post("secret", 25)
+	if rec.Code != http.StatusOK || rec.Header().Get("X-RateLimit-Remaining") != "5" {
+		t.Fatalf("Expected a batch of 25 to use 25 requests, got %d with %s remaining",
+			rec.Code, rec.Header().Get("X-RateLimit-Remaining"))
+	}
+	if rec := post("secret", 6); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected a batch exceeding the remaining requests to be limited, got %d", rec.Code)
+	}
+	if rec := post("secret", 5); rec.Code != http.StatusOK {
+		t.Errorf("Expected a batch within the remaining requests to be allowed, got %d", rec.Code)
+	}
+	if rec := post("small", 11); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected a batch larger than the rate limit to be rejected, got %d", rec.Code)
+	}
+	if rec := post("wrong", 1); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected an invalid key to be rejected, got %d", rec.Code)
+	}
+}
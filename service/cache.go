@@ -0,0 +1,301 @@
+package service
+
+import (
+	"container/list"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/s32x/ovrstat/ovrstat"
+)
+
+// Cache stores player stats lookups keyed by platform and tag
+type Cache interface {
+	Get(key string) (*CacheEntry, bool)
+	Set(key string, entry *CacheEntry) error
+}
+
+// Flusher is implemented by caches that buffer writes, such as a MemoryCache
+// with a snapshot file. Flush is called once the service has shut down, after
+// background refreshes have completed or the shutdown timeout has passed
+type Flusher interface {
+	Flush() error
+}
+
+// Checker is implemented by caches that can check they are writable without
+// storing an entry, used by the /readyz cache check
+type Checker interface {
+	Check() error
+}
+
+// CacheEntry is a single cached stats lookup along with the time it was
+// retrieved from upstream
+type CacheEntry struct {
+	Stats    *ovrstat.PlayerStats `json:"stats"`
+	StoredAt time.Time            `json:"storedAt"`
+}
+
+// Age returns how long ago the entry was retrieved from upstream
+func (e *CacheEntry) Age(now time.Time) time.Duration {
+	return now.Sub(e.StoredAt)
+}
+
+// MemoryCache is an in-memory Cache that evicts the least recently used
+// entry once it holds more than its configured size. A MemoryCache created
+// by LoadMemoryCache persists its entries to a snapshot file when flushed
+type MemoryCache struct {
+	mu       sync.Mutex
+	size     int
+	ll       *list.List
+	items    map[string]*list.Element
+	snapshot string
+}
+
+type memoryItem struct {
+	Key   string      `json:"key"`
+	Entry *CacheEntry `json:"entry"`
+}
+
+// NewMemoryCache creates and returns a new MemoryCache holding at most size
+// entries
+func NewMemoryCache(size int) *MemoryCache {
+	return &MemoryCache{
+		size:  size,
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+// Get retrieves an entry from the cache, marking it as recently used
+func (m *MemoryCache) Get(key string) (*CacheEntry, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	el, ok := m.items[key]
+	if !ok {
+		return nil, false
+	}
+	m.ll.MoveToFront(el)
+	return el.Value.(*memoryItem).Entry, true
+}
+
+// Set stores an entry in the cache, evicting the least recently used entry if
+// the cache is full
+func (m *MemoryCache) Set(key string, entry *CacheEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if el, ok := m.items[key]; ok {
+		el.Value.(*memoryItem).Entry = entry
+		m.ll.MoveToFront(el)
+		return nil
+	}
+	m.items[key] = m.ll.PushFront(&memoryItem{Key: key, Entry: entry})
+	if m.size > 0 && m.ll.Len() > m.size {
+		oldest := m.ll.Back()
+		m.ll.Remove(oldest)
+		delete(m.items, oldest.Value.(*memoryItem).Key)
+	}
+	return nil
+}
+
+// LoadMemoryCache creates a MemoryCache holding at most size entries that is
+// restored from the snapshot file if it exists and saved to it when flushed.
+// The returned cache is usable even if the snapshot couldn't be restored
+func LoadMemoryCache(size int, snapshot string) (*MemoryCache, error) {
+	m := NewMemoryCache(size)
+	m.snapshot = snapshot
+	b, err := os.ReadFile(snapshot)
+	if os.IsNotExist(err) {
+		return m, nil
+	} else if err != nil {
+		return m, errors.Wrap(err, "Failed to read cache snapshot")
+	}
+	var items []memoryItem
+	if err := json.Unmarshal(b, &items); err != nil {
+		return m, errors.Wrap(err, "Failed to decode cache snapshot")
+	}
+	for _, item := range items {
+		m.Set(item.Key, item.Entry)
+	}
+	return m, nil
+}
+
+// Flush saves the caches entries to its snapshot file, least recently used
+// first so restoring them preserves their order. It does nothing if the cache
+// has no snapshot file
+func (m *MemoryCache) Flush() error {
+	if m.snapshot == "" {
+		return nil
+	}
+	m.mu.Lock()
+	items := make([]*memoryItem, 0, m.ll.Len())
+	for el := m.ll.Back(); el != nil; el = el.Prev() {
+		items = append(items, el.Value.(*memoryItem))
+	}
+	b, err := json.Marshal(items)
+	m.mu.Unlock()
+	if err != nil {
+		return errors.Wrap(err, "Failed to encode cache snapshot")
+	}
+	return writeFile(m.snapshot, b)
+}
+
+// Check reports whether the snapshot file can be written. It always succeeds
+// if the cache has no snapshot file
+func (m *MemoryCache) Check() error {
+	if m.snapshot == "" {
+		return nil
+	}
+	return checkDir(filepath.Dir(m.snapshot))
+}
+
+// checkDir reports whether files can be created in dir by creating and
+// removing a temp file, creating dir if needed
+func checkDir(dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return errors.Wrap(err, "Failed to create cache directory")
+	}
+	tmp, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return errors.Wrap(err, "Failed to create cache file")
+	}
+	tmp.Close()
+	return os.Remove(tmp.Name())
+}
+
+// writeFile atomically writes b to path, creating its directory if needed.
+// The data is written to a temp file first so readers never see a partial
+// file
+func writeFile(path string, b []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return errors.Wrap(err, "Failed to create cache directory")
+	}
+	tmp, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return errors.Wrap(err, "Failed to create cache file")
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "Failed to write cache file")
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.Wrap(err, "Failed to write cache file")
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return errors.Wrap(err, "Failed to write cache file")
+	}
+	return nil
+}
+
+// fileSweepInterval is how often a FileCache removes entries beyond its size
+const fileSweepInterval = time.Minute
+
+// FileCache is a Cache that stores each entry as a JSON file in a directory.
+// Once it holds more than its configured size, the least recently stored
+// entries are removed by a sweep run at most once a minute
+type FileCache struct {
+	dir  string
+	size int
+
+	mu        sync.Mutex
+	lastSweep time.Time
+}
+
+// NewFileCache creates and returns a new FileCache storing at most size
+// entries in dir, or any number if size is zero. The directory is created on
+// the first write if it doesn't exist
+func NewFileCache(dir string, size int) *FileCache {
+	return &FileCache{dir: dir, size: size}
+}
+
+// Get reads and decodes an entry from disk
+func (f *FileCache) Get(key string) (*CacheEntry, bool) {
+	b, err := os.ReadFile(f.path(key))
+	if err != nil {
+		return nil, false
+	}
+	var entry CacheEntry
+	if err := json.Unmarshal(b, &entry); err != nil {
+		return nil, false
+	}
+	return &entry, true
+}
+
+// Set encodes and atomically writes an entry to disk
+func (f *FileCache) Set(key string, entry *CacheEntry) error {
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return errors.Wrap(err, "Failed to encode cache entry")
+	}
+	if err := writeFile(f.path(key), b); err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if now := time.Now(); now.Sub(f.lastSweep) >= fileSweepInterval {
+		f.lastSweep = now
+		return f.sweep()
+	}
+	return nil
+}
+
+// Check reports whether entries can be written to the caches directory
+func (f *FileCache) Check() error {
+	return checkDir(f.dir)
+}
+
+// sweep removes the least recently stored entries beyond the caches size,
+// along with temp files left behind by interrupted writes
+func (f *FileCache) sweep() error {
+	dirEntries, err := os.ReadDir(f.dir)
+	if err != nil {
+		return errors.Wrap(err, "Failed to read cache directory")
+	}
+
+	type file struct {
+		path     string
+		storedAt time.Time
+	}
+	var files []file
+	for _, de := range dirEntries {
+		info, err := de.Info()
+		if err != nil || !info.Mode().IsRegular() {
+			continue // Removed since being listed
+		}
+		path := filepath.Join(f.dir, de.Name())
+		switch {
+		case strings.HasPrefix(de.Name(), ".tmp-"):
+			if time.Since(info.ModTime()) > fileSweepInterval {
+				os.Remove(path)
+			}
+		case filepath.Ext(de.Name()) == ".json":
+			files = append(files, file{path, info.ModTime()})
+		}
+	}
+	if f.size <= 0 || len(files) <= f.size {
+		return nil
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].storedAt.After(files[j].storedAt) })
+	for _, fl := range files[f.size:] {
+		if err := os.Remove(fl.path); err != nil && !os.IsNotExist(err) {
+			return errors.Wrap(err, "Failed to remove cache file")
+		}
+	}
+	return nil
+}
+
+// path returns the file path for the passed key
+func (f *FileCache) path(key string) string {
+	sum := sha1.Sum([]byte(key))
+	return filepath.Join(f.dir, hex.EncodeToString(sum[:])+".json")
+}
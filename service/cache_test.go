@@ -0,0 +1,204 @@
+package service
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/s32x/ovrstat/ovrstat"
+)
+
+func TestMemoryCacheEviction(t *testing.T) {
+	c := NewMemoryCache(2)
+	entry := func(name string) *CacheEntry {
+		return &CacheEntry{Stats: &ovrstat.PlayerStats{Name: name}, StoredAt: time.Now()}
+	}
+	c.Set("a", entry("a"))
+	c.Set("b", entry("b"))
+	c.Get("a") // a is now more recently used than b
+	c.Set("c", entry("c"))
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("Expected the least recently used entry to be evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if e, ok := c.Get(key); !ok || e.Stats.Name != key {
+			t.Errorf("Expected %s to be cached, got %+v", key, e)
+		}
+	}
+
+	// Replacing an entry doesn't evict another
+	c.Set("a", entry("a2"))
+	if e, ok := c.Get("a"); !ok || e.Stats.Name != "a2" {
+		t.Errorf("Expected a to be replaced, got %+v", e)
+	}
+	if _, ok := c.Get("c"); !ok {
+		t.Error("Expected c to remain cached")
+	}
+}
+
+func TestMemoryCacheSnapshot(t *testing.T) {
+	snapshot := filepath.Join(t.TempDir(), "snapshot", "cache.json")
+	c, err := LoadMemoryCache(2, snapshot)
+	if err != nil {
+		t.Fatalf("Expected a missing snapshot to start an empty cache, got %v", err)
+	}
+	stored := time.Now().Add(-time.Minute).Round(0)
+	for _, key := range []string{"a", "b"} {
+		c.Set(key, &CacheEntry{Stats: &ovrstat.PlayerStats{Name: key}, StoredAt: stored})
+	}
+	c.Get("a")
+	if err := c.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	restored, err := LoadMemoryCache(2, snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"a", "b"} {
+		if e, ok := restored.Get(key); !ok || e.Stats.Name != key || !e.StoredAt.Equal(stored) {
+			t.Errorf("Expected %s to be restored, got %+v", key, e)
+		}
+	}
+
+	// The least recently used entry is still evicted first once restored
+	restored, _ = LoadMemoryCache(2, snapshot)
+	restored.Set("c", &CacheEntry{StoredAt: stored})
+	if _, ok := restored.Get("b"); ok {
+		t.Error("Expected the restored cache to keep its LRU order")
+	}
+
+	// Corrupt snapshots are reported but leave a usable cache
+	os.WriteFile(snapshot, []byte("{"), 0o644)
+	c, err = LoadMemoryCache(2, snapshot)
+	if err == nil || c == nil {
+		t.Fatalf("Expected a corrupt snapshot to be reported, got %v", err)
+	}
+	if _, ok := c.Get("a"); ok {
+		t.Error("Expected a corrupt snapshot to start an empty cache")
+	}
+	if err := NewMemoryCache(2).Flush(); err != nil {
+		t.Errorf("Expected flushing without a snapshot to do nothing, got %v", err)
+	}
+}
+
+func TestFileCache(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "cache")
+	c := NewFileCache(dir, 2)
+	if _, ok := c.Get("pc/Viz-1213"); ok {
+		t.Fatal("Expected a miss before anything is stored")
+	}
+
+	stored := time.Now().Add(-time.Minute).Round(0)
+	if err := c.Set("pc/Viz-1213", &CacheEntry{Stats: &ovrstat.PlayerStats{Name: "Viz#1213", Level: 52}, StoredAt: stored}); err != nil {
+		t.Fatal(err)
+	}
+	e, ok := NewFileCache(dir, 2).Get("pc/Viz-1213")
+	if !ok || e.Stats.Name != "Viz#1213" || e.Stats.Level != 52 || !e.StoredAt.Equal(stored) {
+		t.Fatalf("Expected the entry to round trip, got %+v", e)
+	}
+
+	// Corrupt entries are misses
+	os.WriteFile(c.path("pc/Broken-0000"), []byte("{"), 0o644)
+	if _, ok := c.Get("pc/Broken-0000"); ok {
+		t.Error("Expected a corrupt entry to be a miss")
+	}
+	os.Remove(c.path("pc/Broken-0000"))
+
+	// Sweeping keeps the most recently stored entries and old temp files go
+	for i, key := range []string{"a", "b", "c"} {
+		if err := c.Set(key, &CacheEntry{StoredAt: time.Now()}); err != nil {
+			t.Fatal(err)
+		}
+		mod := time.Now().Add(time.Duration(i-3) * time.Hour)
+		os.Chtimes(c.path(key), mod, mod)
+	}
+	tmp := filepath.Join(dir, ".tmp-interrupted")
+	os.WriteFile(tmp, nil, 0o644)
+	old := time.Now().Add(-time.Hour)
+	os.Chtimes(tmp, old, old)
+	if err := c.sweep(); err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]bool{"pc/Viz-1213": true, "c": true, "b": false, "a": false} {
+		if _, ok := c.Get(key); ok != want {
+			t.Errorf("Expected %s cached %v after sweeping, got %v", key, want, ok)
+		}
+	}
+	if _, err := os.Stat(tmp); !os.IsNotExist(err) {
+		t.Errorf("Expected the stale temp file to be removed, got %v", err)
+	}
+}
+
+func TestStatsCaching(t *testing.T) {
+	var careers atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fixture := filepath.Join("search", "viz.json")
+		if strings.HasPrefix(r.URL.Path, "/career/") {
+			careers.Add(1)
+			fixture = filepath.Join("career", "pc-public.html")
+		}
+		http.ServeFile(w, r, filepath.Join("..", "ovrstat", "testdata", fixture))
+	}))
+	defer srv.Close()
+
+	cache := NewMemoryCache(10)
+	cfg := DefaultConfig()
+	cfg.Upstream.BaseURL = srv.URL + "/career"
+	cfg.Upstream.APIURL = srv.URL + "/search/"
+	cfg.Log.Level = slog.LevelError
+	cfg.Cache.Store = cache
+	cfg.Cache.TTL = 10 * time.Minute
+	cfg.Cache.StaleWhileRevalidate = 10 * time.Minute
+	cfg.Cache.PlatformTTL = map[string]time.Duration{ovrstat.PlatformPSN: time.Hour}
+	e, h := newService(cfg)
+	get := func(path string) (status, age string) {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d %s", path, rec.Code, rec.Body)
+		}
+		return rec.Header().Get("X-Cache"), rec.Header().Get("Age")
+	}
+	age := func(key string, d time.Duration) {
+		e, _ := cache.Get(key)
+		cache.Set(key, &CacheEntry{Stats: e.Stats, StoredAt: time.Now().Add(-d)})
+	}
+
+	if status, a := get("/v2/stats/pc/Viz-1213"); status != cacheMiss || a != "0" || careers.Load() != 1 {
+		t.Errorf("Expected a miss fetched upstream, got %s Age %s after %d fetches", status, a, careers.Load())
+	}
+	if status, _ := get("/v2/stats/pc/Viz-1213"); status != cacheHit || careers.Load() != 1 {
+		t.Errorf("Expected a hit, got %s after %d fetches", status, careers.Load())
+	}
+
+	// Expired entries within the stale-while-revalidate window are served
+	// while being refreshed in the background
+	age("pc/Viz-1213", 15*time.Minute)
+	if status, a := get("/v2/stats/pc/Viz-1213"); status != cacheStale || a != "900" {
+		t.Errorf("Expected a stale entry aged 900s, got %s Age %s", status, a)
+	}
+	h.refreshes.Wait()
+	if e, _ := cache.Get("pc/Viz-1213"); careers.Load() != 2 || e.Age(time.Now()) > time.Minute {
+		t.Errorf("Expected the stale entry to be refreshed, got %d fetches and age %v", careers.Load(), e.Age(time.Now()))
+	}
+
+	// Entries past the window are fetched again before responding
+	age("pc/Viz-1213", 25*time.Minute)
+	if status, _ := get("/v2/stats/pc/Viz-1213"); status != cacheMiss || careers.Load() != 3 {
+		t.Errorf("Expected an expired entry to be fetched again, got %s after %d fetches", status, careers.Load())
+	}
+
+	// Platforms can have their own TTL
+	cache.Set("psn/Viz", &CacheEntry{Stats: &ovrstat.PlayerStats{Name: "Viz"}, StoredAt: time.Now().Add(-30 * time.Minute)})
+	if status, a := get("/v2/stats/psn/Viz"); status != cacheHit || a != "1800" || careers.Load() != 3 {
+		t.Errorf("Expected the psn TTL to keep the entry fresh, got %s Age %s after %d fetches", status, a, careers.Load())
+	}
+}
@@ -0,0 +1,298 @@
+package service
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/s32x/ovrstat/ovrstat"
+	"golang.org/x/time/rate"
+)
+
+// Log formats supported by the service
+const (
+	LogFormatJSON = "json"
+	LogFormatText = "text"
+)
+
+// Cache backends supported by the service
+const (
+	CacheNone   = "none"
+	CacheMemory = "memory"
+	CacheFile   = "file"
+)
+
+// Config holds all configuration for the service
+type Config struct {
+	// Addr is the TCP address the service listens on
+	Addr string `yaml:"addr"`
+
+	Server   ServerConfig   `yaml:"server"`
+	Cache    CacheConfig    `yaml:"cache"`
+	Upstream UpstreamConfig `yaml:"upstream"`
+	Auth     AuthConfig     `yaml:"auth"`
+	CORS     CORSConfig     `yaml:"cors"`
+	Log      LogConfig      `yaml:"log"`
+
+	Readiness ReadinessConfig `yaml:"readiness"`
+	API       APIConfig       `yaml:"api"`
+	Batch     BatchConfig     `yaml:"batch"`
+
+	// AccountResolution chooses between accounts matching a lookup. With
+	// ovrstat.ResolveFail ambiguous lookups are served as 300 Multiple
+	// Choices listing the candidates
+	AccountResolution ovrstat.Resolution `yaml:"accountResolution"`
+
+	// CaseInsensitiveTags looks up tags differing from the players only in
+	// case through the search API. Responses name the canonical tag in the
+	// Content-Location header, or are redirected to it with CanonicalRedirect
+	CaseInsensitiveTags bool `yaml:"caseInsensitiveTags"`
+	CanonicalRedirect   bool `yaml:"canonicalRedirect"`
+
+	// Metrics serves Prometheus metrics at /metrics
+	Metrics bool `yaml:"metrics"`
+}
+
+// ServerConfig configures the HTTP server and its shutdown
+type ServerConfig struct {
+	// ReadTimeout, ReadHeaderTimeout, WriteTimeout and IdleTimeout are the
+	// timeouts of the underlying http.Server, zero means no timeout
+	ReadTimeout       time.Duration `yaml:"readTimeout"`
+	ReadHeaderTimeout time.Duration `yaml:"readHeaderTimeout"`
+	WriteTimeout      time.Duration `yaml:"writeTimeout"`
+	IdleTimeout       time.Duration `yaml:"idleTimeout"`
+
+	// DrainDelay is how long /healthcheck reports unhealthy before the server
+	// stops accepting connections, giving load balancers time to notice
+	DrainDelay time.Duration `yaml:"drainDelay"`
+
+	// ShutdownTimeout bounds how long in-flight requests and background
+	// refreshes are waited on when shutting down
+	ShutdownTimeout time.Duration `yaml:"shutdownTimeout"`
+
+	// TrustedProxies lists the IPs or CIDR ranges of proxies trusted to name
+	// the client in X-Forwarded-For. Without any the client IP is the address
+	// of the connection and forwarding headers are ignored
+	TrustedProxies []string `yaml:"trustedProxies"`
+}
+
+// CacheConfig configures caching of stats lookups
+type CacheConfig struct {
+	// Backend is one of CacheNone, CacheMemory or CacheFile
+	Backend string `yaml:"backend"`
+
+	// Store, if set, is used instead of the cache described by Backend
+	Store Cache `yaml:"-"`
+
+	// Size is the maximum number of entries held by the cache, zero means
+	// unbounded
+	Size int `yaml:"size"`
+
+	// Dir is the directory entries are stored in by the file cache
+	Dir string `yaml:"dir"`
+
+	// Snapshot is the file the memory cache is restored from at startup and
+	// saved to on shutdown, empty disables persisting it
+	Snapshot string `yaml:"snapshot"`
+
+	// TTL is how long an entry is considered fresh, PlatformTTL overrides it
+	// for individual platforms
+	TTL         time.Duration            `yaml:"ttl"`
+	PlatformTTL map[string]time.Duration `yaml:"platformTTL"`
+
+	// StaleWhileRevalidate is how long after expiring an entry may still be
+	// served while it is refreshed in the background
+	StaleWhileRevalidate time.Duration `yaml:"staleWhileRevalidate"`
+
+	// RefreshTimeout bounds each background refresh
+	RefreshTimeout time.Duration `yaml:"refreshTimeout"`
+}
+
+// UpstreamConfig configures how the service talks to the Overwatch stats site
+type UpstreamConfig struct {
+	// BaseURL and APIURL override the career page and search API URLs,
+	// defaulting to playoverwatch.com when empty
+	BaseURL string `yaml:"baseURL"`
+	APIURL  string `yaml:"apiURL"`
+
+	// Timeout bounds each upstream request attempt, zero means no timeout
+	Timeout time.Duration `yaml:"timeout"`
+
+	// RateLimit is the maximum number of upstream requests per second with
+	// bursts of up to Burst requests, zero disables the limit
+	RateLimit float64 `yaml:"rateLimit"`
+	Burst     int     `yaml:"burst"`
+
+	// BreakerThreshold is the number of consecutive upstream failures after
+	// which requests are paused for BreakerCooldown, zero disables the breaker
+	BreakerThreshold int           `yaml:"breakerThreshold"`
+	BreakerCooldown  time.Duration `yaml:"breakerCooldown"`
+}
+
+// AuthConfig configures API keys and the rate limits of API clients. Rate
+// limits of zero or less are unlimited
+type AuthConfig struct {
+	// Keys are the accepted API keys, passed in the X-API-Key header or the
+	// api_key query parameter
+	Keys []APIKey `yaml:"keys"`
+
+	// KeysFile names a file keys are additionally loaded from, see LoadAPIKeys
+	KeysFile string `yaml:"keysFile"`
+
+	// KeyRequestsPerMinute is the rate limit of keys without their own
+	KeyRequestsPerMinute int `yaml:"keyRequestsPerMinute"`
+
+	// AllowAnonymous allows requests without an API key, limited per IP to
+	// AnonymousRequestsPerMinute
+	AllowAnonymous             bool `yaml:"allowAnonymous"`
+	AnonymousRequestsPerMinute int  `yaml:"anonymousRequestsPerMinute"`
+}
+
+// CORSConfig configures cross-origin requests
+type CORSConfig struct {
+	// AllowOrigins are the origins allowed to call the API, "*" allows any
+	AllowOrigins []string `yaml:"allowOrigins"`
+}
+
+// ReadinessConfig configures the checks served at /readyz
+type ReadinessConfig struct {
+	// CanaryPlatform and CanaryTag identify a known public profile that is
+	// looked up to check upstream is reachable and the parser still works.
+	// Upstream checks are skipped unless both are set
+	CanaryPlatform string `yaml:"canaryPlatform"`
+	CanaryTag      string `yaml:"canaryTag"`
+
+	// Interval is how long the result of the checks is reused for, limiting
+	// the upstream requests made by frequent readiness probes
+	Interval time.Duration `yaml:"interval"`
+
+	// Timeout bounds each run of the checks
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// APIConfig configures the versions of the stats API. /v1 and the unversioned
+// routes serve PlayerStats as is, /v2 serves a cleaned up schema
+type APIConfig struct {
+	// V1Deprecation and V1Sunset, when set, are announced on every v1
+	// response in the Deprecation and Sunset headers
+	V1Deprecation time.Time `yaml:"v1Deprecation,omitempty"`
+	V1Sunset      time.Time `yaml:"v1Sunset,omitempty"`
+}
+
+// BatchConfig configures batch stats requests
+type BatchConfig struct {
+	// MaxPlayers is the most players a single batch may list
+	MaxPlayers int `yaml:"maxPlayers"`
+
+	// Workers is how many players of a batch are looked up concurrently
+	Workers int `yaml:"workers"`
+}
+
+// LogConfig configures the services structured logs
+type LogConfig struct {
+	// Level is the minimum level of records that are logged
+	Level slog.Level `yaml:"level"`
+
+	// Format is one of LogFormatJSON or LogFormatText
+	Format string `yaml:"format"`
+}
+
+// DefaultConfig returns the configuration used when none is provided
+func DefaultConfig() Config {
+	return Config{
+		Addr: ":8080",
+		Server: ServerConfig{
+			ReadTimeout:       10 * time.Second,
+			ReadHeaderTimeout: 5 * time.Second,
+			WriteTimeout:      2 * time.Minute,
+			IdleTimeout:       2 * time.Minute,
+			ShutdownTimeout:   30 * time.Second,
+		},
+		Cache: CacheConfig{
+			Backend:              CacheMemory,
+			Size:                 10000,
+			Dir:                  "cache",
+			TTL:                  10 * time.Minute,
+			StaleWhileRevalidate: time.Hour,
+			RefreshTimeout:       30 * time.Second,
+		},
+		Upstream: UpstreamConfig{
+			Timeout:          30 * time.Second,
+			RateLimit:        10,
+			Burst:            20,
+			BreakerThreshold: 5,
+			BreakerCooldown:  30 * time.Second,
+		},
+		Auth: AuthConfig{
+			AllowAnonymous:             true,
+			AnonymousRequestsPerMinute: 30,
+			KeyRequestsPerMinute:       300,
+		},
+		CORS: CORSConfig{
+			AllowOrigins: []string{"*"},
+		},
+		Log: LogConfig{
+			Level:  slog.LevelInfo,
+			Format: LogFormatJSON,
+		},
+		Readiness: ReadinessConfig{
+			Interval: time.Minute,
+			Timeout:  15 * time.Second,
+		},
+		Batch: BatchConfig{
+			MaxPlayers: 25,
+			Workers:    ovrstat.DefaultBatchWorkers,
+		},
+		AccountResolution: ovrstat.ResolveExact,
+		Metrics:           true,
+	}
+}
+
+// ttl returns the freshness lifetime of entries for the passed platform
+func (c CacheConfig) ttl(platform string) time.Duration {
+	if d, ok := c.PlatformTTL[platform]; ok {
+		return d
+	}
+	return c.TTL
+}
+
+// newCache creates the Cache described by the config, returning nil if
+// caching is disabled. A memory cache snapshot that can't be restored is
+// logged and the cache started empty
+func (c CacheConfig) newCache(logger *slog.Logger) Cache {
+	if c.Store != nil {
+		return c.Store
+	}
+	switch c.Backend {
+	case CacheMemory:
+		if c.Snapshot == "" {
+			return NewMemoryCache(c.Size)
+		}
+		m, err := LoadMemoryCache(c.Size, c.Snapshot)
+		if err != nil {
+			logger.Warn("failed to restore cache snapshot", "path", c.Snapshot, "error", err)
+		}
+		return m
+	case CacheFile:
+		return NewFileCache(c.Dir, c.Size)
+	default:
+		return nil
+	}
+}
+
+// newLimiter creates the outbound rate limiter described by the config,
+// returning nil if the rate is unlimited
+func (c UpstreamConfig) newLimiter() *rate.Limiter {
+	if c.RateLimit <= 0 {
+		return nil
+	}
+	return rate.NewLimiter(rate.Limit(c.RateLimit), c.Burst)
+}
+
+// newBreaker creates the circuit breaker described by the config, returning
+// nil if it's disabled
+func (c UpstreamConfig) newBreaker() *ovrstat.CircuitBreaker {
+	if c.BreakerThreshold <= 0 {
+		return nil
+	}
+	return ovrstat.NewCircuitBreaker(c.BreakerThreshold, c.BreakerCooldown)
+}
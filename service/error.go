@@ -1,17 +1,198 @@
 package service
 
-import "github.com/labstack/echo/v4"
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
 
-// newErr creates and returns a new echo HTTPError with the passed status code
-// and optional message. Message expected to be of type string or error
-func newErr(code int, message ...interface{}) error {
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"github.com/s32x/ovrstat/ovrstat"
+)
+
+// Machine readable error codes served in error responses
+const (
+	codeBadRequest          = "bad_request"
+	codeMissingAPIKey       = "missing_api_key"
+	codeInvalidAPIKey       = "invalid_api_key"
+	codeRateLimited         = "rate_limited"
+	codeNotFound            = "not_found"
+	codeInvalidPlatform     = "invalid_platform"
+	codeInvalidTag          = "invalid_tag"
+	codePlayerNotFound      = "player_not_found"
+	codePrivateProfile      = "private_profile"
+	codeAmbiguousPlayer     = "ambiguous_player"
+	codeUpstreamRateLimited = "upstream_rate_limited"
+	codeUpstreamTimeout     = "upstream_timeout"
+	codeUpstreamUnavailable = "upstream_unavailable"
+	codeUpstreamCircuitOpen = "upstream_circuit_open"
+	codeUpstreamThrottled   = "upstream_throttled"
+	codeUpstreamError       = "upstream_error"
+	codeLayoutChanged       = "layout_changed"
+	codeInternal            = "internal_error"
+)
+
+// apiError is an error served to clients as a JSON body containing a machine
+// readable code and a human readable message
+type apiError struct {
+	Status     int           `json:"-"`
+	Code       string        `json:"code"`
+	Message    string        `json:"message"`
+	RetryAfter time.Duration `json:"-"`
+
+	// Candidates lists the accounts matching an ambiguous lookup
+	Candidates []ovrstat.Platform `json:"candidates,omitempty"`
+}
+
+// Error returns the errors message
+func (e *apiError) Error() string {
+	return e.Message
+}
+
+// newErr creates and returns a new apiError with the passed status code,
+// error code and optional message. Message expected to be of type string or
+// error
+func newErr(status int, code string, message ...interface{}) *apiError {
+	e := &apiError{Status: status, Code: code, Message: "An error has occurred"}
 	if len(message) > 0 {
 		switch v := message[0].(type) {
 		case error:
-			return echo.NewHTTPError(code, v.Error())
+			e.Message = v.Error()
 		case string:
-			return echo.NewHTTPError(code, v)
+			e.Message = v
+		}
+	}
+	return e
+}
+
+// statsErr maps an error returned by a stats lookup to the apiError served to
+// the client
+func statsErr(err error) *apiError {
+	var ue *ovrstat.UpstreamError
+	switch {
+	case errors.Is(err, ovrstat.ErrInvalidPlatform):
+		return newErr(http.StatusBadRequest, codeInvalidPlatform, "Invalid platform")
+	case errors.Is(err, ovrstat.ErrInvalidTag):
+		return newErr(http.StatusBadRequest, codeInvalidTag, err)
+	case errors.Is(err, ovrstat.ErrInvalidSearch):
+		return newErr(http.StatusBadRequest, codeBadRequest, "Missing search query")
+	case errors.Is(err, ovrstat.ErrPlayerNotFound):
+		return newErr(http.StatusNotFound, codePlayerNotFound, "Player not found")
+	case errors.Is(err, ovrstat.ErrPrivateProfile):
+		return newErr(http.StatusForbidden, codePrivateProfile, "Player profile is private")
+	case errors.Is(err, ovrstat.ErrAmbiguousPlayer):
+		return ambiguousErr(err)
+	case errors.Is(err, ovrstat.ErrRateLimited):
+		ae := newErr(http.StatusTooManyRequests, codeUpstreamRateLimited,
+			"Rate limited by the Overwatch stats site, try again later")
+		if errors.As(err, &ue) {
+			ae.RetryAfter = ue.RetryAfter
 		}
+		return ae
+	case errors.Is(err, ovrstat.ErrCircuitOpen):
+		return newErr(http.StatusServiceUnavailable, codeUpstreamCircuitOpen,
+			"The Overwatch stats site is failing, requests are paused")
+	case errors.Is(err, ovrstat.ErrThrottled):
+		return newErr(http.StatusServiceUnavailable, codeUpstreamThrottled,
+			"Too many requests to the Overwatch stats site, try again later")
+	case errors.Is(err, ovrstat.ErrUpstreamTimeout):
+		return newErr(http.StatusGatewayTimeout, codeUpstreamTimeout,
+			"Timed out retrieving stats from the Overwatch stats site")
+	case errors.As(err, &ue) && ue.StatusCode >= http.StatusInternalServerError:
+		return newErr(http.StatusServiceUnavailable, codeUpstreamUnavailable,
+			"The Overwatch stats site is unavailable")
+	case errors.As(err, &ue):
+		return newErr(http.StatusBadGateway, codeUpstreamError, err)
+	case errors.Is(err, ovrstat.ErrLayoutChanged):
+		return newErr(http.StatusBadGateway, codeLayoutChanged, err)
+	}
+	return newErr(http.StatusInternalServerError, codeInternal,
+		errors.Wrap(err, "Failed to retrieve player stats"))
+}
+
+// ambiguousErr returns the apiError served for an ambiguous lookup, listing
+// the candidate accounts so the client can retry with the id of one. Lookups
+// configured to fail on ambiguity are served as 300 Multiple Choices, lookups
+// the configured resolution couldn't settle as 409 Conflict
+func ambiguousErr(err error) *apiError {
+	var ae *ovrstat.AmbiguousError
+	if !errors.As(err, &ae) {
+		return newErr(http.StatusConflict, codeAmbiguousPlayer, err)
+	}
+	status := http.StatusConflict
+	if ae.Resolution == ovrstat.ResolveFail {
+		status = http.StatusMultipleChoices
+	}
+	e := newErr(status, codeAmbiguousPlayer, fmt.Sprintf(
+		"%d accounts match %s, retry with the id of one of the candidates", len(ae.Candidates), ae.Tag))
+	e.Candidates = ae.Candidates
+	return e
+}
+
+// newErrorHandler returns an echo.HTTPErrorHandler serving every error
+// returned by a handler or middleware as an apiError JSON body
+func newErrorHandler(logger *slog.Logger) echo.HTTPErrorHandler {
+	return func(err error, c echo.Context) {
+		if c.Response().Committed {
+			return
+		}
+		ctx := c.Request().Context()
+
+		var ae *apiError
+		var he *echo.HTTPError
+		switch {
+		case errors.As(err, &ae):
+		case errors.As(err, &he):
+			ae = &apiError{Status: he.Code, Code: codeForStatus(he.Code), Message: fmt.Sprint(he.Message)}
+		default:
+			logger.ErrorContext(ctx, "unhandled error", "error", err)
+			ae = &apiError{Status: http.StatusInternalServerError, Code: codeInternal,
+				Message: http.StatusText(http.StatusInternalServerError)}
+		}
+
+		if ae.RetryAfter > 0 {
+			c.Response().Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(ae.RetryAfter.Seconds()))))
+		}
+		if c.Request().Method == http.MethodHead {
+			err = c.NoContent(ae.Status)
+		} else {
+			err = c.JSON(ae.Status, ae)
+		}
+		if err != nil {
+			logger.ErrorContext(ctx, "failed to serve error response", "error", err)
+		}
+	}
+}
+
+// responseStatus returns the status served for a request, resolving the
+// status the error handler will serve for the passed error
+func responseStatus(c echo.Context, err error) int {
+	var ae *apiError
+	var he *echo.HTTPError
+	switch {
+	case errors.As(err, &ae):
+		return ae.Status
+	case errors.As(err, &he):
+		return he.Code
+	case err != nil:
+		return http.StatusInternalServerError
+	}
+	return c.Response().Status
+}
+
+// codeForStatus returns the error code used for errors raised by echo itself
+func codeForStatus(status int) string {
+	switch {
+	case status == http.StatusNotFound:
+		return codeNotFound
+	case status == http.StatusTooManyRequests:
+		return codeRateLimited
+	case status < http.StatusInternalServerError:
+		return codeBadRequest
+	default:
+		return codeInternal
 	}
-	return echo.NewHTTPError(code, "An error has occurred")
 }
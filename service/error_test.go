@@ -0,0 +1,123 @@
+package service
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/s32x/ovrstat/ovrstat"
+)
+
+func TestStatsErr(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{ovrstat.ErrInvalidPlatform, http.StatusBadRequest, codeInvalidPlatform},
+		{&ovrstat.TagError{Platform: ovrstat.PlatformPC, Tag: "Viz", Reason: "bad"}, http.StatusBadRequest, codeInvalidTag},
+		{ovrstat.ErrPlayerNotFound, http.StatusNotFound, codePlayerNotFound},
+		{errors.Wrap(ovrstat.ErrPrivateProfile, "wrapped"), http.StatusForbidden, codePrivateProfile},
+		{ovrstat.ErrAmbiguousPlayer, http.StatusConflict, codeAmbiguousPlayer},
+		{&ovrstat.AmbiguousError{Resolution: ovrstat.ResolveExact}, http.StatusConflict, codeAmbiguousPlayer},
+		{&ovrstat.AmbiguousError{Resolution: ovrstat.ResolveFail}, http.StatusMultipleChoices, codeAmbiguousPlayer},
+		{&ovrstat.UpstreamError{StatusCode: http.StatusTooManyRequests}, http.StatusTooManyRequests, codeUpstreamRateLimited},
+		{&ovrstat.UpstreamError{StatusCode: http.StatusBadGateway}, http.StatusServiceUnavailable, codeUpstreamUnavailable},
+		{&ovrstat.UpstreamError{StatusCode: http.StatusForbidden}, http.StatusBadGateway, codeUpstreamError},
+		{&ovrstat.ParseError{Source: "career page", Err: errors.New("bad")}, http.StatusBadGateway, codeLayoutChanged},
+		{errors.New("boom"), http.StatusInternalServerError, codeInternal},
+	}
+	for _, tt := range tests {
+		var ae *apiError
+		if !errors.As(statsErr(tt.err), &ae) {
+			t.Fatalf("statsErr(%v) did not return an apiError", tt.err)
+		}
+		if ae.Status != tt.status || ae.Code != tt.code {
+			t.Errorf("statsErr(%v) = %d %s, want %d %s", tt.err, ae.Status, ae.Code, tt.status, tt.code)
+		}
+	}
+}
+
+func TestStatsAmbiguous(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fixture := map[string]string{
+			"/career/psn/TayuyaBreast": filepath.Join("career", "psn-public.html"),
+			"/career/psn/tayuyabreast": filepath.Join("career", "pc-private.html"),
+			"/search/TayuyaBreast":     filepath.Join("search", "tayuya-case.json"),
+		}[r.URL.Path]
+		if fixture == "" {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeFile(w, r, filepath.Join("..", "ovrstat", "testdata", fixture))
+	}))
+	defer srv.Close()
+
+	cfg := DefaultConfig()
+	cfg.Upstream.BaseURL = srv.URL + "/career"
+	cfg.Upstream.APIURL = srv.URL + "/search/"
+	cfg.Cache.Backend = CacheNone
+	cfg.Log.Level = slog.LevelError
+	cfg.AccountResolution = ovrstat.ResolveFail
+	e, _ := newService(cfg)
+	get := func(target string, v interface{}) int {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		json.Unmarshal(rec.Body.Bytes(), v)
+		return rec.Code
+	}
+
+	var ae apiError
+	code := get("/v2/stats/psn/TayuyaBreast", &ae)
+	if code != http.StatusMultipleChoices || ae.Code != codeAmbiguousPlayer || len(ae.Candidates) != 2 {
+		t.Fatalf("Expected 300 with both psn candidates, got %d %+v", code, ae)
+	}
+
+	// Retrying with a candidates ID serves that accounts career page
+	var stats playerStatsV2
+	code = get("/v2/stats/psn/TayuyaBreast?id=3004", &stats)
+	if code != http.StatusOK || stats.ID != 3004 || stats.Tag != "tayuyabreast" ||
+		stats.Prestige != 3 || stats.Level != 17 {
+		t.Errorf("Expected account 3004 with its own career, got %d %+v", code, stats)
+	}
+	if code := get("/v2/stats/psn/TayuyaBreast?id=3003", &ae); code != http.StatusNotFound {
+		t.Errorf("Expected an ID on another platform not to be found, got %d", code)
+	}
+	if code := get("/v2/stats/psn/TayuyaBreast?id=3001", &ae); code != http.StatusNotFound {
+		t.Errorf("Expected an ID with another tag not to be found, got %d", code)
+	}
+	if code := get("/v2/stats/psn/TayuyaBreast?id=x", &ae); code != http.StatusBadRequest {
+		t.Errorf("Expected an invalid ID to be rejected, got %d", code)
+	}
+}
+
+func TestStatsInvalidTag(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	cfg := DefaultConfig()
+	cfg.Upstream.BaseURL = srv.URL + "/career"
+	cfg.Upstream.APIURL = srv.URL + "/search/"
+	cfg.Log.Level = slog.LevelError
+	e, _ := newService(cfg)
+	for _, path := range []string{"/stats/pc/Viz", "/v2/stats/psn/a%20b", "/v2/stats/nintendo-switch/Mario"} {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		var ae apiError
+		json.Unmarshal(rec.Body.Bytes(), &ae)
+		if rec.Code != http.StatusBadRequest || ae.Code != codeInvalidTag {
+			t.Errorf("%s: expected 400 %s, got %d %+v", path, codeInvalidTag, rec.Code, ae)
+		}
+	}
+	if requests != 0 {
+		t.Errorf("Expected no upstream requests, got %d", requests)
+	}
+}
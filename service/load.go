@@ -0,0 +1,325 @@
+package service
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/s32x/ovrstat/ovrstat"
+	"gopkg.in/yaml.v3"
+)
+
+// setting is a single configuration value that can be set by an environment
+// variable and a command-line flag
+type setting struct {
+	flag  string
+	env   string
+	usage string
+	set   func(string) error
+}
+
+// settings returns every setting bound to the fields of the passed config.
+// Settings are applied in order, so PORT is listed before ADDR to let the
+// latter take precedence
+func settings(c *Config) []setting {
+	return []setting{
+		{"", "PORT", "port to listen on, shorthand for ADDR=:PORT", func(v string) error {
+			c.Addr = ":" + v
+			return nil
+		}},
+		{"addr", "ADDR", "TCP address to listen on", setString(&c.Addr)},
+
+		{"read-timeout", "SERVER_READ_TIMEOUT", "timeout reading requests", setDuration(&c.Server.ReadTimeout)},
+		{"read-header-timeout", "SERVER_READ_HEADER_TIMEOUT", "timeout reading request headers", setDuration(&c.Server.ReadHeaderTimeout)},
+		{"write-timeout", "SERVER_WRITE_TIMEOUT", "timeout writing responses", setDuration(&c.Server.WriteTimeout)},
+		{"idle-timeout", "SERVER_IDLE_TIMEOUT", "how long idle keep-alive connections are kept", setDuration(&c.Server.IdleTimeout)},
+		{"drain-delay", "SERVER_DRAIN_DELAY",
+			"how long /healthcheck fails before shutting down", setDuration(&c.Server.DrainDelay)},
+		{"shutdown-timeout", "SERVER_SHUTDOWN_TIMEOUT",
+			"how long in-flight requests are waited on when shutting down", setDuration(&c.Server.ShutdownTimeout)},
+		{"trusted-proxies", "SERVER_TRUSTED_PROXIES",
+			"comma separated IPs or CIDRs of proxies trusted to set X-Forwarded-For", func(v string) error {
+				c.Server.TrustedProxies = splitList(v)
+				return nil
+			}},
+
+		{"cache-backend", "CACHE_BACKEND", "cache backend: none, memory or file", setString(&c.Cache.Backend)},
+		{"cache-size", "CACHE_SIZE", "maximum entries held by the cache", setInt(&c.Cache.Size)},
+		{"cache-dir", "CACHE_DIR", "directory used by the file cache", setString(&c.Cache.Dir)},
+		{"cache-snapshot", "CACHE_SNAPSHOT", "file the memory cache is restored from and saved to on shutdown", setString(&c.Cache.Snapshot)},
+		{"cache-ttl", "CACHE_TTL", "how long cached stats are fresh", setDuration(&c.Cache.TTL)},
+		{"cache-stale-while-revalidate", "CACHE_STALE_WHILE_REVALIDATE",
+			"how long expired stats are served while refreshed", setDuration(&c.Cache.StaleWhileRevalidate)},
+		{"cache-refresh-timeout", "CACHE_REFRESH_TIMEOUT", "timeout of background refreshes", setDuration(&c.Cache.RefreshTimeout)},
+
+		{"upstream-base-url", "UPSTREAM_BASE_URL", "base URL career pages are scraped from", setString(&c.Upstream.BaseURL)},
+		{"upstream-api-url", "UPSTREAM_API_URL", "URL of the account search API", setString(&c.Upstream.APIURL)},
+		{"upstream-timeout", "UPSTREAM_TIMEOUT", "timeout of each upstream request", setDuration(&c.Upstream.Timeout)},
+		{"upstream-rate-limit", "UPSTREAM_RATE_LIMIT", "upstream requests per second, 0 is unlimited", setFloat(&c.Upstream.RateLimit)},
+		{"upstream-burst", "UPSTREAM_BURST", "upstream request burst size", setInt(&c.Upstream.Burst)},
+		{"upstream-breaker-threshold", "UPSTREAM_BREAKER_THRESHOLD",
+			"consecutive upstream failures that open the circuit breaker, 0 disables it", setInt(&c.Upstream.BreakerThreshold)},
+		{"upstream-breaker-cooldown", "UPSTREAM_BREAKER_COOLDOWN",
+			"how long the circuit breaker stays open", setDuration(&c.Upstream.BreakerCooldown)},
+
+		{"api-keys", "API_KEYS", "comma separated API keys", func(v string) error {
+			c.Auth.Keys = nil
+			for _, k := range splitList(v) {
+				c.Auth.Keys = append(c.Auth.Keys, APIKey{Key: k})
+			}
+			return nil
+		}},
+		{"api-keys-file", "API_KEYS_FILE", "file API keys are loaded from", setString(&c.Auth.KeysFile)},
+		{"api-key-rpm", "API_KEY_RPM", "requests per minute allowed per API key", setInt(&c.Auth.KeyRequestsPerMinute)},
+		{"allow-anonymous", "ALLOW_ANONYMOUS", "allow requests without an API key", setBool(&c.Auth.AllowAnonymous)},
+		{"anonymous-rpm", "ANONYMOUS_RPM", "requests per minute allowed per anonymous IP", setInt(&c.Auth.AnonymousRequestsPerMinute)},
+
+		{"cors-origins", "CORS_ORIGINS", "comma separated origins allowed to call the API", func(v string) error {
+			c.CORS.AllowOrigins = splitList(v)
+			return nil
+		}},
+
+		{"log-level", "LOG_LEVEL", "minimum log level: debug, info, warn or error", func(v string) error {
+			return c.Log.Level.UnmarshalText([]byte(v))
+		}},
+		{"log-format", "LOG_FORMAT", "log format: json or text", setString(&c.Log.Format)},
+
+		{"ready-canary-platform", "READY_CANARY_PLATFORM",
+			"platform of the profile looked up by /readyz, empty skips upstream checks", setString(&c.Readiness.CanaryPlatform)},
+		{"ready-canary-tag", "READY_CANARY_TAG",
+			"tag of the profile looked up by /readyz, empty skips upstream checks", setString(&c.Readiness.CanaryTag)},
+		{"ready-interval", "READY_INTERVAL", "how long /readyz results are reused", setDuration(&c.Readiness.Interval)},
+		{"ready-timeout", "READY_TIMEOUT", "timeout of the /readyz checks", setDuration(&c.Readiness.Timeout)},
+
+		{"api-v1-deprecation", "API_V1_DEPRECATION",
+			"date v1 was deprecated, announced in the Deprecation header", setTime(&c.API.V1Deprecation)},
+		{"api-v1-sunset", "API_V1_SUNSET", "date v1 will be removed, announced in the Sunset header", setTime(&c.API.V1Sunset)},
+
+		{"batch-max-players", "BATCH_MAX_PLAYERS", "most players a batch request may list", setInt(&c.Batch.MaxPlayers)},
+		{"batch-workers", "BATCH_WORKERS", "players of a batch looked up concurrently", setInt(&c.Batch.Workers)},
+
+		{"account-resolution", "ACCOUNT_RESOLUTION",
+			"how lookups matching several accounts are resolved: exact, case-insensitive, by-id or fail", func(v string) error {
+				c.AccountResolution = ovrstat.Resolution(v)
+				return nil
+			}},
+
+		{"case-insensitive-tags", "CASE_INSENSITIVE_TAGS",
+			"look up tags ignoring case through the search API", setBool(&c.CaseInsensitiveTags)},
+		{"canonical-redirect", "CANONICAL_REDIRECT",
+			"redirect lookups to the URL of the canonical tag", setBool(&c.CanonicalRedirect)},
+
+		{"metrics", "METRICS", "serve Prometheus metrics at /metrics", setBool(&c.Metrics)},
+	}
+}
+
+// LoadConfig builds the services configuration, registering a flag for every
+// setting on fs and parsing args. Settings are taken from, in order of
+// precedence, command-line flags, environment variables, the YAML file named
+// by the -config flag or CONFIG_FILE variable, and DefaultConfig
+func LoadConfig(fs *flag.FlagSet, args []string, lookupEnv func(string) (string, bool)) (Config, error) {
+	cfg := DefaultConfig()
+	ss := settings(&cfg)
+
+	// Flags are recorded while parsing and applied last
+	type flagValue struct {
+		s setting
+		v string
+	}
+	var flags []flagValue
+	for _, s := range ss {
+		if s.flag == "" {
+			continue
+		}
+		s := s
+		fs.Func(s.flag, fmt.Sprintf("%s (env %s)", s.usage, s.env), func(v string) error {
+			flags = append(flags, flagValue{s, v})
+			return nil
+		})
+	}
+	path := fs.String("config", "", "YAML config file (env CONFIG_FILE)")
+	if err := fs.Parse(args); err != nil {
+		return cfg, err
+	}
+
+	// Apply the config file, then the environment, then flags
+	if *path == "" {
+		*path, _ = lookupEnv("CONFIG_FILE")
+	}
+	if *path != "" {
+		if err := cfg.loadFile(*path); err != nil {
+			return cfg, err
+		}
+	}
+	for _, s := range ss {
+		if v, ok := lookupEnv(s.env); ok {
+			if err := s.set(v); err != nil {
+				return cfg, errors.Wrapf(err, "Invalid %s", s.env)
+			}
+		}
+	}
+	for _, f := range flags {
+		if err := f.s.set(f.v); err != nil {
+			return cfg, errors.Wrapf(err, "Invalid -%s", f.s.flag)
+		}
+	}
+
+	if cfg.Auth.KeysFile != "" {
+		keys, err := LoadAPIKeys(cfg.Auth.KeysFile)
+		if err != nil {
+			return cfg, err
+		}
+		cfg.Auth.Keys = append(cfg.Auth.Keys, keys...)
+	}
+	return cfg, cfg.Validate()
+}
+
+// loadFile decodes the YAML config file at path over the config, rejecting
+// unknown fields
+func (c *Config) loadFile(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return errors.Wrap(err, "Failed to read config file")
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil {
+		return errors.Wrapf(err, "Failed to parse config file %s", path)
+	}
+	return nil
+}
+
+// Validate reports the first invalid setting in the config
+func (c Config) Validate() error {
+	switch c.Cache.Backend {
+	case CacheNone, CacheMemory, CacheFile:
+	default:
+		return errors.Errorf("Invalid cache backend %q", c.Cache.Backend)
+	}
+	switch c.Log.Format {
+	case LogFormatJSON, LogFormatText:
+	default:
+		return errors.Errorf("Invalid log format %q", c.Log.Format)
+	}
+	if !c.AccountResolution.Valid() {
+		return errors.Errorf("Invalid account resolution %q", c.AccountResolution)
+	}
+	if c.Batch.MaxPlayers < 1 || c.Batch.Workers < 1 {
+		return errors.New("Batch max players and workers must be at least 1")
+	}
+	if (c.Readiness.CanaryPlatform == "") != (c.Readiness.CanaryTag == "") {
+		return errors.New("Readiness canary platform and tag must be set together")
+	}
+	if _, err := c.Server.ipExtractor(); err != nil {
+		return err
+	}
+	if c.Upstream.RateLimit > 0 && c.Upstream.Burst < 1 {
+		return errors.New("Upstream burst must be at least 1 when rate limited")
+	}
+	return nil
+}
+
+// YAML encodes the config as YAML with API keys redacted, suitable for
+// printing or as a config file template
+func (c Config) YAML() ([]byte, error) {
+	keys := make([]APIKey, len(c.Auth.Keys))
+	for i, k := range c.Auth.Keys {
+		keys[i] = APIKey{Key: "REDACTED", RequestsPerMinute: k.RequestsPerMinute}
+	}
+	c.Auth.Keys = keys
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(c); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), enc.Close()
+}
+
+// setString returns a setter for a string setting
+func setString(p *string) func(string) error {
+	return func(v string) error {
+		*p = v
+		return nil
+	}
+}
+
+// setInt returns a setter for an integer setting
+func setInt(p *int) func(string) error {
+	return func(v string) error {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return err
+		}
+		*p = n
+		return nil
+	}
+}
+
+// setFloat returns a setter for a float setting
+func setFloat(p *float64) func(string) error {
+	return func(v string) error {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return err
+		}
+		*p = f
+		return nil
+	}
+}
+
+// setBool returns a setter for a boolean setting
+func setBool(p *bool) func(string) error {
+	return func(v string) error {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return err
+		}
+		*p = b
+		return nil
+	}
+}
+
+// setDuration returns a setter for a duration setting
+func setDuration(p *time.Duration) func(string) error {
+	return func(v string) error {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return err
+		}
+		*p = d
+		return nil
+	}
+}
+
+// setTime returns a setter for a time setting, accepting RFC 3339 timestamps
+// or dates
+func setTime(p *time.Time) func(string) error {
+	return func(v string) error {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			if t, err = time.Parse(time.DateOnly, v); err != nil {
+				return err
+			}
+		}
+		*p = t
+		return nil
+	}
+}
+
+// splitList splits a comma separated list, dropping empty items
+func splitList(v string) []string {
+	var items []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
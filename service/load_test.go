@@ -0,0 +1,86 @@
+package service
+
+import (
+	"flag"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(path, []byte(`
+addr: ":9000"
+cache:
+  size: 5
+  ttl: 1m
+  platformTTL:
+    psn: 20m
+log:
+  level: debug
+`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := map[string]string{
+		"CONFIG_FILE":  path,
+		"CACHE_TTL":    "2m",
+		"CORS_ORIGINS": "https://a.example, https://b.example",
+		"METRICS":      "false",
+	}
+	lookupEnv := func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	}
+
+	fs := flag.NewFlagSet("ovrstat", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	cfg, err := LoadConfig(fs, []string{"-cache-ttl", "3m", "-upstream-rate-limit=0"}, lookupEnv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Flags override the environment, which overrides the file and defaults
+	if cfg.Cache.TTL != 3*time.Minute {
+		t.Errorf("Expected the flag TTL to win, got %s", cfg.Cache.TTL)
+	}
+	if cfg.Cache.Size != 5 || cfg.Addr != ":9000" || cfg.Cache.ttl("psn") != 20*time.Minute {
+		t.Errorf("Expected file settings to apply, got %+v", cfg)
+	}
+	if cfg.Log.Level != slog.LevelDebug || cfg.Metrics || len(cfg.CORS.AllowOrigins) != 2 {
+		t.Errorf("Expected file and env settings to apply, got %+v", cfg)
+	}
+	if cfg.Upstream.RateLimit != 0 || cfg.Upstream.Burst != 20 {
+		t.Errorf("Expected untouched settings to keep their defaults, got %+v", cfg.Upstream)
+	}
+}
+
+func TestLoadConfigInvalid(t *testing.T) {
+	for name, tc := range map[string]struct {
+		args []string
+		env  map[string]string
+	}{
+		"flag":    {args: []string{"-cache-size", "lots"}},
+		"env":     {env: map[string]string{"UPSTREAM_TIMEOUT": "soon"}},
+		"backend": {env: map[string]string{"CACHE_BACKEND": "redis"}},
+		"resolve": {args: []string{"-account-resolution", "first"}},
+		"proxies": {args: []string{"-trusted-proxies", "10.0.0.0/8,proxy"}},
+		"canary":  {args: []string{"-ready-canary-tag", "Viz-1213"}},
+		"file":    {env: map[string]string{"CONFIG_FILE": "missing.yaml"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			fs := flag.NewFlagSet("ovrstat", flag.ContinueOnError)
+			fs.SetOutput(io.Discard)
+			_, err := LoadConfig(fs, tc.args, func(key string) (string, bool) {
+				v, ok := tc.env[key]
+				return v, ok
+			})
+			if err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/s32x/ovrstat/ovrstat"
+)
+
+// newLogger creates the logger described by the config, writing to stdout and
+// attaching request IDs to every record
+func (c LogConfig) newLogger() *slog.Logger {
+	opts := &slog.HandlerOptions{Level: c.Level}
+	var h slog.Handler
+	switch c.Format {
+	case LogFormatText:
+		h = slog.NewTextHandler(os.Stdout, opts)
+	default:
+		h = slog.NewJSONHandler(os.Stdout, opts)
+	}
+	return slog.New(ovrstat.LogHandler(h))
+}
+
+// requestID assigns every request an ID, reusing the X-Request-ID header if
+// the client sent one. The ID is echoed in the response and carried by the
+// request context so it's logged and forwarded upstream
+func requestID() echo.MiddlewareFunc {
+	return middleware.RequestIDWithConfig(middleware.RequestIDConfig{
+		RequestIDHandler: func(c echo.Context, id string) {
+			req := c.Request()
+			c.SetRequest(req.WithContext(ovrstat.WithRequestID(req.Context(), id)))
+		},
+	})
+}
+
+// accessLog logs every request once it has been served
+func accessLog(logger *slog.Logger) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			err := next(c)
+
+			req := c.Request()
+			status := responseStatus(c, err)
+			level := slog.LevelInfo
+			if status >= 500 {
+				level = slog.LevelError
+			}
+			attrs := []slog.Attr{
+				slog.String("method", req.Method),
+				slog.String("uri", req.RequestURI),
+				slog.String("route", c.Path()),
+				slog.Int("status", status),
+				slog.String("remote_ip", c.RealIP()),
+				slog.String("user_agent", req.UserAgent()),
+				slog.Duration("latency", time.Since(start)),
+			}
+			if err != nil {
+				attrs = append(attrs, slog.String("error", err.Error()))
+			}
+			logger.LogAttrs(req.Context(), level, "request", attrs...)
+			return err
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/s32x/ovrstat/ovrstat"
+)
+
+// Results of a stats lookup recorded per platform
+const (
+	lookupOK        = "ok"
+	lookupPrivate   = "private"
+	lookupNotFound  = "not_found"
+	lookupAmbiguous = "ambiguous"
+	lookupError     = "error"
+)
+
+// metrics holds the Prometheus metrics exposed by the service
+type metrics struct {
+	registry         *prometheus.Registry
+	requests         *prometheus.CounterVec
+	requestDuration  *prometheus.HistogramVec
+	upstreamDuration *prometheus.HistogramVec
+	parseDuration    prometheus.Histogram
+	cacheLookups     *prometheus.CounterVec
+	lookups          *prometheus.CounterVec
+	inflight         prometheus.Gauge
+}
+
+// newMetrics creates and registers all service metrics, including gauges
+// reporting the clients coalescing and circuit breaker state
+func newMetrics(h *handler) *metrics {
+	m := &metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "ovrstat_http_requests_total",
+			Help: "HTTP requests served, by route, method and status.",
+		}, []string{"route", "method", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "ovrstat_http_request_duration_seconds",
+			Help:    "HTTP request latency, by route, method and status.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+		upstreamDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "ovrstat_upstream_request_duration_seconds",
+			Help:    "Upstream request latency, by endpoint (career or search) and status.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"endpoint", "status"}),
+		parseDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "ovrstat_parse_duration_seconds",
+			Help:    "Time spent parsing career pages.",
+			Buckets: prometheus.ExponentialBuckets(0.001, 2, 12),
+		}),
+		cacheLookups: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "ovrstat_cache_lookups_total",
+			Help: "Stats cache lookups, by result (hit, miss or stale).",
+		}, []string{"result"}),
+		lookups: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "ovrstat_lookups_total",
+			Help: "Upstream stats lookups, by platform and result (ok, private, not_found, ambiguous or error).",
+		}, []string{"platform", "result"}),
+		inflight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "ovrstat_inflight_scrapes",
+			Help: "Upstream stats lookups currently in flight.",
+		}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests, m.requestDuration, m.upstreamDuration, m.parseDuration,
+		m.cacheLookups, m.lookups, m.inflight,
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "ovrstat_coalesced_lookups_total",
+			Help: "Lookups that shared an upstream fetch already in flight.",
+		}, func() float64 {
+			return float64(h.client.CoalesceStats().Coalesced)
+		}),
+	)
+	if h.breaker != nil {
+		m.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "ovrstat_upstream_breaker_state",
+			Help: "State of the upstream circuit breaker (0 closed, 1 open, 2 half-open).",
+		}, func() float64 { return float64(h.breaker.State()) }))
+	}
+	return m
+}
+
+// middleware records the count and latency of every request by route
+func (m *metrics) middleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		start := time.Now()
+		err := next(c)
+
+		labels := prometheus.Labels{
+			"route":  c.Path(),
+			"method": c.Request().Method,
+			"status": strconv.Itoa(responseStatus(c, err)),
+		}
+		m.requests.With(labels).Inc()
+		m.requestDuration.With(labels).Observe(time.Since(start).Seconds())
+		return err
+	}
+}
+
+// hooks returns the client hooks recording upstream, parse and lookup metrics
+func (m *metrics) hooks() ovrstat.Hooks {
+	return ovrstat.Hooks{
+		Upstream: func(ctx context.Context, ev ovrstat.UpstreamEvent) {
+			status := strconv.Itoa(ev.StatusCode)
+			if ev.Err != nil {
+				status = "error"
+			}
+			m.upstreamDuration.WithLabelValues(ev.Endpoint, status).Observe(ev.Duration.Seconds())
+		},
+		Parse: func(ctx context.Context, d time.Duration, err error) {
+			m.parseDuration.Observe(d.Seconds())
+		},
+		LookupStart: func(ctx context.Context, pid ovrstat.PlayerID, id int) {
+			m.inflight.Inc()
+		},
+		Lookup: func(ctx context.Context, ev ovrstat.LookupEvent) {
+			m.inflight.Dec()
+			m.lookup(ev.Player.Platform, ev.Stats, ev.Err)
+		},
+	}
+}
+
+// lookup records the result of an upstream stats lookup
+func (m *metrics) lookup(platform string, stats *ovrstat.PlayerStats, err error) {
+	result := lookupOK
+	switch {
+	case errors.Is(err, ovrstat.ErrInvalidPlatform):
+		return
+	case errors.Is(err, ovrstat.ErrPlayerNotFound):
+		result = lookupNotFound
+	case errors.Is(err, ovrstat.ErrPrivateProfile):
+		result = lookupPrivate
+	case errors.Is(err, ovrstat.ErrAmbiguousPlayer):
+		result = lookupAmbiguous
+	case err != nil:
+		result = lookupError
+	case stats.Private:
+		result = lookupPrivate
+	}
+	m.lookups.WithLabelValues(platform, result).Inc()
+}
+
+// handler returns the handler serving the metrics in the Prometheus format
+func (m *metrics) handler() echo.HandlerFunc {
+	return echo.WrapHandler(promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{}))
+}
@@ -0,0 +1,366 @@
+package service
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/s32x/ovrstat/internal/flight"
+	"github.com/s32x/ovrstat/ovrstat"
+)
+
+// schema is an OpenAPI 3 schema object
+type schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Items                *schema            `json:"items,omitempty"`
+	Properties           map[string]*schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties interface{}        `json:"additionalProperties,omitempty"`
+}
+
+// object is a JSON object in the OpenAPI document
+type object = map[string]interface{}
+
+// schemaNames renames types whose Go names make poor schema names
+var schemaNames = map[reflect.Type]string{
+	reflect.TypeOf(apiError{}):           "Error",
+	reflect.TypeOf(status{}):             "Status",
+	reflect.TypeOf(rateLimitStatus{}):    "RateLimitStatus",
+	reflect.TypeOf(flight.Stats{}):       "CoalesceStats",
+	reflect.TypeOf(readyReport{}):        "Readiness",
+	reflect.TypeOf(readyCheck{}):         "ReadinessCheck",
+	reflect.TypeOf(playerStatsV1{}):      "PlayerStats",
+	reflect.TypeOf(ratingV1{}):           "RatingV1",
+	reflect.TypeOf(quickPlayStatsV1{}):   "QuickPlayStatsCollection",
+	reflect.TypeOf(competitiveStatsV1{}): "CompetitiveStatsCollection",
+	reflect.TypeOf(topHeroV1{}):          "TopHeroStats",
+	reflect.TypeOf(careerStatsV1{}):      "CareerStats",
+	reflect.TypeOf(playerStatsV2{}):      "PlayerStatsV2",
+	reflect.TypeOf(searchResults{}):      "SearchResults",
+	reflect.TypeOf(endorsementV2{}):      "EndorsementV2",
+	reflect.TypeOf(modeStatsV2{}):        "ModeStatsV2",
+	reflect.TypeOf(competitiveStatsV2{}): "CompetitiveStatsV2",
+	reflect.TypeOf(topHeroV2{}):          "TopHeroV2",
+
+	reflect.TypeOf(batchRequest{}):                 "BatchRequest",
+	reflect.TypeOf(batchResponse[playerStatsV1]{}): "BatchResponse",
+	reflect.TypeOf(batchResult[playerStatsV1]{}):   "BatchResult",
+	reflect.TypeOf(batchResponse[playerStatsV2]{}): "BatchResponseV2",
+	reflect.TypeOf(batchResult[playerStatsV2]{}):   "BatchResultV2",
+}
+
+// schemaOverrides describes types whose JSON encoding differs from their kind
+var schemaOverrides = map[reflect.Type]schema{
+	reflect.TypeOf(time.Time{}):             {Type: "string", Format: "date-time"},
+	reflect.TypeOf(ovrstat.Duration(0)):     {Type: "number", Description: "A duration in seconds"},
+	reflect.TypeOf(ovrstat.BreakerState(0)): {Type: "string", Enum: []string{"closed", "open", "half-open"}},
+	reflect.TypeOf(ovrstat.Unit("")): {Type: "string", Enum: []string{
+		string(ovrstat.UnitCount), string(ovrstat.UnitPercent), string(ovrstat.UnitSeconds), string(ovrstat.UnitText),
+	}},
+}
+
+var textMarshaler = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+// schemas generates the component schemas of the OpenAPI document from the Go
+// types served by the API, so the document can't drift from the models
+type schemas map[string]*schema
+
+// of returns the schema of the passed type, adding the schemas of any named
+// struct types it contains to the components and referencing them
+func (s schemas) of(t reflect.Type) *schema {
+	if o, ok := schemaOverrides[t]; ok {
+		return &o
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		sc := s.of(t.Elem())
+		if sc.Ref == "" {
+			sc.Nullable = true
+		}
+		return sc
+	case reflect.Struct:
+		name := schemaNames[t]
+		if name == "" {
+			name = t.Name()
+		}
+		if _, ok := s[name]; !ok {
+			sc := &schema{Type: "object", Properties: make(map[string]*schema)}
+			s[name] = sc
+			s.fields(sc, t)
+		}
+		return &schema{Ref: "#/components/schemas/" + name}
+	case reflect.Slice:
+		// Nil slices and maps are encoded as null
+		return &schema{Type: "array", Items: s.of(t.Elem()), Nullable: true}
+	case reflect.Map:
+		return &schema{Type: "object", AdditionalProperties: s.of(t.Elem()), Nullable: true}
+	case reflect.Interface:
+		return &schema{Description: "Any JSON value"}
+	case reflect.Bool:
+		return &schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &schema{Type: "number"}
+	case reflect.String:
+		return &schema{Type: "string"}
+	}
+	if t.Implements(textMarshaler) {
+		return &schema{Type: "string"}
+	}
+	panic(fmt.Sprintf("openapi: unsupported type %s", t))
+}
+
+// fields adds the JSON encoded fields of a struct type to its schema,
+// flattening embedded structs as encoding/json does
+func (s schemas) fields(sc *schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" || (!f.IsExported() && !f.Anonymous) {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			s.fields(sc, f.Type)
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		sc.Properties[name] = s.of(f.Type)
+		if !strings.Contains(opts, "omitempty") {
+			sc.Required = append(sc.Required, name)
+		}
+	}
+}
+
+// openAPI generates the OpenAPI 3 document describing the service
+func openAPI() object {
+	s := make(schemas)
+	ref := func(v interface{}) *schema { return s.of(reflect.TypeOf(v)) }
+	content := func(sc *schema) object {
+		return object{"application/json": object{"schema": sc}}
+	}
+	errorResponse := func(description string) object {
+		return object{"description": description, "content": content(ref(apiError{}))}
+	}
+	header := func(description, typ string) object {
+		return object{"description": description, "schema": object{"type": typ}}
+	}
+
+	// statsOperation describes a stats lookup route serving the passed schema,
+	// v1 routes also announce their deprecation
+	statsOperation := func(id, summary string, body *schema, v1 bool) object {
+		headers := object{
+			"X-Cache":               header("Whether the stats were served from the cache: HIT, MISS or STALE", "string"),
+			"Age":                   header("Seconds since the stats were retrieved from upstream", "integer"),
+			"X-RateLimit-Limit":     header("Requests allowed per minute", "integer"),
+			"X-RateLimit-Remaining": header("Requests remaining in the current window", "integer"),
+			"X-RateLimit-Reset":     header("Seconds until the limit is fully replenished", "integer"),
+			"Content-Location":      header("The URL of the canonical tag, if the request used another form of it", "string"),
+		}
+		if v1 {
+			headers["Deprecation"] = header("When v1 was deprecated, as @ followed by a Unix timestamp", "string")
+			headers["Sunset"] = header("When v1 will be removed, as an HTTP date", "string")
+			headers["Link"] = header("The v2 equivalent of the request, as the successor-version", "string")
+		}
+		return object{
+			"get": object{
+				"summary":     summary,
+				"operationId": id,
+				"security":    []object{{}, {"apiKeyHeader": []string{}}, {"apiKeyQuery": []string{}}},
+				"parameters": []object{
+					{"name": "platform", "in": "path", "required": true, "schema": object{
+						"type": "string",
+						"enum": []string{ovrstat.PlatformPC, ovrstat.PlatformPSN, ovrstat.PlatformXBL, ovrstat.PlatformNS},
+					}},
+					{"name": "tag", "in": "path", "required": true,
+						"description": "The players BattleTag (e.g. Viz-1213), gamertag or Switch ID",
+						"schema":      object{"type": "string"}},
+					{"name": "id", "in": "query",
+						"description": "The ID of the account to retrieve when several match the tag",
+						"schema":      object{"type": "integer", "minimum": 1}},
+				},
+				"responses": object{
+					"200": object{"description": "The players stats", "headers": headers, "content": content(body)},
+					"300": errorResponse("Multiple players match the tag, listed as candidates"),
+					"301": object{"description": "Redirect to the URL of the canonical tag, if configured",
+						"headers": object{"Location": header("The URL of the canonical tag", "string")}},
+					"400": errorResponse("Invalid platform, tag or account ID"),
+					"401": errorResponse("Missing or invalid API key"),
+					"403": errorResponse("The players profile is private"),
+					"404": errorResponse("Player not found"),
+					"409": errorResponse("Multiple players match the tag and none could be chosen, listed as candidates"),
+					"429": errorResponse("Rate limited, by the service or upstream"),
+					"502": errorResponse("Upstream returned an unexpected response or the career page layout changed"),
+					"503": errorResponse("Upstream is unavailable or requests to it are paused"),
+					"504": errorResponse("Timed out retrieving stats from upstream"),
+				},
+			},
+		}
+	}
+
+	// searchOperation describes an account search route
+	searchOperation := func(id string, v1 bool) object {
+		headers := object{}
+		if v1 {
+			headers["Deprecation"] = header("When v1 was deprecated, as @ followed by a Unix timestamp", "string")
+			headers["Sunset"] = header("When v1 will be removed, as an HTTP date", "string")
+		}
+		return object{
+			"get": object{
+				"summary":     "Search for accounts by name on every platform",
+				"operationId": id,
+				"security":    []object{{}, {"apiKeyHeader": []string{}}, {"apiKeyQuery": []string{}}},
+				"parameters": []object{
+					{"name": "q", "in": "query", "required": true,
+						"description": "A players name or full BattleTag (e.g. Viz#1213)",
+						"schema":      object{"type": "string"}},
+					{"name": "platform", "in": "query", "description": "Only return accounts on this platform",
+						"schema": object{
+							"type": "string",
+							"enum": []string{ovrstat.PlatformPC, ovrstat.PlatformPSN, ovrstat.PlatformXBL, ovrstat.PlatformNS},
+						}},
+					{"name": "page", "in": "query", "schema": object{"type": "integer", "minimum": 1, "default": 1}},
+					{"name": "perPage", "in": "query",
+						"schema": object{"type": "integer", "minimum": 1, "maximum": maxPerPage, "default": defaultPerPage}},
+				},
+				"responses": object{
+					"200": object{"description": "A page of matching accounts", "headers": headers,
+						"content": content(ref(searchResults{}))},
+					"400": errorResponse("Missing query, invalid platform or invalid pagination"),
+					"401": errorResponse("Missing or invalid API key"),
+					"429": errorResponse("Rate limited, by the service or upstream"),
+					"502": errorResponse("Upstream returned an unexpected response"),
+					"503": errorResponse("Upstream is unavailable or requests to it are paused"),
+					"504": errorResponse("Timed out searching upstream"),
+				},
+			},
+		}
+	}
+
+	// batchOperation describes a batch stats route serving the passed schema
+	batchOperation := func(id, summary string, body *schema, v1 bool) object {
+		headers := object{
+			"X-RateLimit-Limit":     header("Requests allowed per minute", "integer"),
+			"X-RateLimit-Remaining": header("Requests remaining in the current window", "integer"),
+			"X-RateLimit-Reset":     header("Seconds until the limit is fully replenished", "integer"),
+		}
+		if v1 {
+			headers["Deprecation"] = header("When v1 was deprecated, as @ followed by a Unix timestamp", "string")
+			headers["Sunset"] = header("When v1 will be removed, as an HTTP date", "string")
+			headers["Link"] = header("The v2 equivalent of the request, as the successor-version", "string")
+		}
+		return object{
+			"post": object{
+				"summary":     summary,
+				"operationId": id,
+				"security":    []object{{}, {"apiKeyHeader": []string{}}, {"apiKeyQuery": []string{}}},
+				"requestBody": object{"required": true, "content": content(ref(batchRequest{}))},
+				"responses": object{
+					"200": object{
+						"description": "A result for every player in the order requested, each holding the " +
+							"players stats or the error their lookup failed with",
+						"headers": headers,
+						"content": content(body),
+					},
+					"400": errorResponse("Invalid request body or too many players"),
+					"401": errorResponse("Missing or invalid API key"),
+					"413": errorResponse("Request body too large"),
+					"429": errorResponse("Rate limited"),
+				},
+			},
+		}
+	}
+
+	paths := object{
+		"/search":    searchOperation("search", true),
+		"/v1/search": searchOperation("searchV1", true),
+		"/v2/search": searchOperation("searchV2", false),
+		"/stats/{platform}/{tag}": statsOperation("getStats",
+			"Retrieve player stats, an alias of /v1/stats", ref(playerStatsV1{}), true),
+		"/v1/stats/{platform}/{tag}": statsOperation("getStatsV1",
+			"Retrieve player stats using the v1 schema", ref(playerStatsV1{}), true),
+		"/v2/stats/{platform}/{tag}": statsOperation("getStatsV2",
+			"Retrieve player stats using the v2 schema", ref(playerStatsV2{}), false),
+		"/stats/batch": batchOperation("getStatsBatch",
+			"Retrieve the stats of several players, an alias of /v1/stats/batch",
+			ref(batchResponse[playerStatsV1]{}), true),
+		"/v1/stats/batch": batchOperation("getStatsBatchV1",
+			"Retrieve the stats of several players using the v1 schema",
+			ref(batchResponse[playerStatsV1]{}), true),
+		"/v2/stats/batch": batchOperation("getStatsBatchV2",
+			"Retrieve the stats of several players using the v2 schema",
+			ref(batchResponse[playerStatsV2]{}), false),
+		"/status": object{
+			"get": object{
+				"summary":     "Report the state of upstream protections",
+				"operationId": "getStatus",
+				"responses": object{
+					"200": object{"description": "The services status", "content": content(ref(status{}))},
+				},
+			},
+		},
+		"/readyz": object{
+			"get": object{
+				"summary":     "Check whether the service can serve lookups",
+				"operationId": "getReadiness",
+				"responses": object{
+					"200": object{"description": "Every check passed", "content": content(ref(readyReport{}))},
+					"503": object{"description": "A check failed", "content": content(ref(readyReport{}))},
+				},
+			},
+		},
+		"/healthcheck": object{
+			"get": object{
+				"summary":     "Check whether the service is serving",
+				"operationId": "getHealth",
+				"responses": object{
+					"200": object{"description": "The service is serving"},
+					"503": object{"description": "The service is shutting down"},
+				},
+			},
+		},
+	}
+
+	return object{
+		"openapi": "3.0.3",
+		"info": object{
+			"title":       "ovrstat",
+			"description": "An unofficial Overwatch stats API",
+			"license":     object{"name": "BSD-3-Clause"},
+			"version":     "1",
+		},
+		"paths": paths,
+		"components": object{
+			"schemas": s,
+			"securitySchemes": object{
+				"apiKeyHeader": object{"type": "apiKey", "in": "header", "name": apiKeyHeader},
+				"apiKeyQuery":  object{"type": "apiKey", "in": "query", "name": apiKeyQuery},
+			},
+		},
+	}
+}
+
+// openAPIHandler serves the OpenAPI document, encoded once up front
+func openAPIHandler() echo.HandlerFunc {
+	b, err := json.MarshalIndent(openAPI(), "", "  ")
+	if err != nil {
+		panic(err)
+	}
+	return func(c echo.Context) error {
+		return c.JSONBlob(http.StatusOK, b)
+	}
+}
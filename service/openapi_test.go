@@ -0,0 +1,178 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/s32x/ovrstat/ovrstat"
+)
+
+var update = flag.Bool("update", false, "update the OpenAPI golden file")
+
+// TestOpenAPIGolden fails when the models change without the committed spec
+// being regenerated with -update
+func TestOpenAPIGolden(t *testing.T) {
+	got, err := json.MarshalIndent(openAPI(), "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = append(got, '\n')
+
+	path := filepath.Join("testdata", "openapi.json")
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read %s, run with -update to create it: %v", path, err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("The OpenAPI spec differs from %s, run with -update if the change is intended", path)
+	}
+}
+
+// TestOpenAPIModels validates real responses against the spec, catching models
+// whose JSON encoding the generated schemas don't describe
+func TestOpenAPIModels(t *testing.T) {
+	var doc struct {
+		Components struct {
+			Schemas map[string]*schema `json:"schemas"`
+		} `json:"components"`
+	}
+	b, _ := json.Marshal(openAPI())
+	if err := json.Unmarshal(b, &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	goldens, err := filepath.Glob(filepath.Join("..", "ovrstat", "testdata", "golden", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range goldens {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var v map[string]interface{}
+		if err := json.Unmarshal(b, &v); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := v["error"]; ok {
+			continue // A golden error, not stats
+		}
+		// The same stats served by v1 and v2
+		var ps ovrstat.PlayerStats
+		if err := json.Unmarshal(b, &ps); err != nil {
+			t.Fatal(err)
+		}
+		for _, version := range []struct {
+			name, schema string
+			render       func(*ovrstat.PlayerStats) interface{}
+		}{
+			{"v1", "PlayerStats", func(ps *ovrstat.PlayerStats) interface{} { return newPlayerStatsV1(ps) }},
+			{"v2", "PlayerStatsV2", func(ps *ovrstat.PlayerStats) interface{} { return newPlayerStatsV2(ps) }},
+		} {
+			b, _ = json.Marshal(version.render(&ps))
+			v = nil
+			json.Unmarshal(b, &v)
+			ref := &schema{Ref: "#/components/schemas/" + version.schema}
+			for _, err := range validate(doc.Components.Schemas, ref, v, "$") {
+				t.Errorf("%s (%s): %v", filepath.Base(path), version.name, err)
+			}
+		}
+	}
+
+	// The pinned v1 responses
+	goldens, err = filepath.Glob(filepath.Join("testdata", "v1", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range goldens {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var v interface{}
+		if err := json.Unmarshal(b, &v); err != nil {
+			t.Fatal(err)
+		}
+		ref := &schema{Ref: "#/components/schemas/PlayerStats"}
+		for _, err := range validate(doc.Components.Schemas, ref, v, "$") {
+			t.Errorf("%s (v1): %v", filepath.Base(path), err)
+		}
+	}
+}
+
+// validate reports where v doesn't match the schema
+func validate(schemas map[string]*schema, sc *schema, v interface{}, path string) []error {
+	if sc.Ref != "" {
+		return validate(schemas, schemas[strings.TrimPrefix(sc.Ref, "#/components/schemas/")], v, path)
+	}
+	if v == nil {
+		if sc.Nullable || sc.Type == "" {
+			return nil
+		}
+		return []error{fmt.Errorf("%s: unexpected null", path)}
+	}
+
+	var errs []error
+	switch sc.Type {
+	case "object":
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return []error{fmt.Errorf("%s: expected an object, got %T", path, v)}
+		}
+		for _, name := range sc.Required {
+			if _, ok := obj[name]; !ok {
+				errs = append(errs, fmt.Errorf("%s: missing required property %q", path, name))
+			}
+		}
+		for name, pv := range obj {
+			ps := sc.Properties[name]
+			if ps == nil && sc.AdditionalProperties != nil {
+				b, _ := json.Marshal(sc.AdditionalProperties)
+				ps = new(schema)
+				json.Unmarshal(b, ps)
+			}
+			if ps == nil {
+				errs = append(errs, fmt.Errorf("%s: undocumented property %q", path, name))
+				continue
+			}
+			errs = append(errs, validate(schemas, ps, pv, path+"."+name)...)
+		}
+	case "array":
+		arr, ok := v.([]interface{})
+		if !ok {
+			return []error{fmt.Errorf("%s: expected an array, got %T", path, v)}
+		}
+		for i, item := range arr {
+			errs = append(errs, validate(schemas, sc.Items, item, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	case "integer":
+		if f, ok := v.(float64); !ok || f != float64(int64(f)) {
+			errs = append(errs, fmt.Errorf("%s: expected an integer, got %v", path, v))
+		}
+	case "number":
+		if _, ok := v.(float64); !ok {
+			errs = append(errs, fmt.Errorf("%s: expected a number, got %T", path, v))
+		}
+	case "string":
+		if _, ok := v.(string); !ok {
+			errs = append(errs, fmt.Errorf("%s: expected a string, got %T", path, v))
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			errs = append(errs, fmt.Errorf("%s: expected a boolean, got %T", path, v))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,221 @@
+package service
+
+import (
+	"bufio"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+)
+
+// Where clients may pass their API key
+const (
+	apiKeyHeader = "X-API-Key"
+	apiKeyQuery  = "api_key"
+)
+
+// APIKey is a key granting access to the API, optionally with its own rate
+// limit in requests per minute
+type APIKey struct {
+	Key               string `yaml:"key"`
+	RequestsPerMinute int    `yaml:"requestsPerMinute,omitempty"`
+}
+
+// LoadAPIKeys reads API keys from a file containing one key per line,
+// optionally followed by whitespace and the keys requests per minute. Blank
+// lines and lines starting with # are ignored
+func LoadAPIKeys(path string) ([]APIKey, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to open API keys file")
+	}
+	defer f.Close()
+
+	var keys []APIKey
+	sc := bufio.NewScanner(f)
+	for line := 1; sc.Scan(); line++ {
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		key := APIKey{Key: fields[0]}
+		if len(fields) > 1 {
+			if key.RequestsPerMinute, err = strconv.Atoi(fields[1]); err != nil {
+				return nil, errors.Errorf("Invalid rate limit on line %d of API keys file", line)
+			}
+		}
+		keys = append(keys, key)
+	}
+	return keys, errors.Wrap(sc.Err(), "Failed to read API keys file")
+}
+
+// ipExtractor returns how the client IP is found, which rate limits anonymous
+// clients. Only trusted proxies may name it in X-Forwarded-For, otherwise any
+// client could claim a fresh IP on every request. Invalid proxies fall back
+// to the address of the connection along with the error
+func (s ServerConfig) ipExtractor() (echo.IPExtractor, error) {
+	if len(s.TrustedProxies) == 0 {
+		return echo.ExtractIPDirect(), nil
+	}
+	opts := []echo.TrustOption{
+		echo.TrustLoopback(false),
+		echo.TrustLinkLocal(false),
+		echo.TrustPrivateNet(false),
+	}
+	for _, p := range s.TrustedProxies {
+		cidr := p
+		if !strings.Contains(cidr, "/") {
+			if ip := net.ParseIP(cidr); ip != nil && ip.To4() != nil {
+				cidr += "/32"
+			} else {
+				cidr += "/128"
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return echo.ExtractIPDirect(), errors.Errorf("Invalid trusted proxy %q", p)
+		}
+		opts = append(opts, echo.TrustIPRange(ipNet))
+	}
+	return echo.ExtractIPFromXFFHeader(opts...), nil
+}
+
+// rateLimiter authenticates API keys and limits the request rate of each API
+// key and each anonymous client IP with a token bucket
+type rateLimiter struct {
+	cfg  AuthConfig
+	keys map[string]int // Requests per minute of every valid key
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// bucket is a token bucket holding up to a minutes worth of requests
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// newRateLimiter creates a rateLimiter for the passed config
+func newRateLimiter(cfg AuthConfig) *rateLimiter {
+	rl := &rateLimiter{
+		cfg:     cfg,
+		keys:    make(map[string]int),
+		buckets: make(map[string]*bucket),
+	}
+	for _, k := range cfg.Keys {
+		rpm := k.RequestsPerMinute
+		if rpm == 0 {
+			rpm = cfg.KeyRequestsPerMinute
+		}
+		rl.keys[k.Key] = rpm
+	}
+	return rl
+}
+
+// middleware authenticates the request and enforces the callers rate limit,
+// setting the X-RateLimit-* headers on every limited response
+func (rl *rateLimiter) middleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if err := rl.limit(c, 1); err != nil {
+			return err
+		}
+		return next(c)
+	}
+}
+
+// limit authenticates the request and charges cost requests to the callers
+// rate limit, setting the X-RateLimit-* headers
+func (rl *rateLimiter) limit(c echo.Context, cost int) error {
+	key := c.Request().Header.Get(apiKeyHeader)
+	if key == "" {
+		key = c.QueryParam(apiKeyQuery)
+	}
+
+	var id string
+	var rpm int
+	switch {
+	case key != "":
+		var ok bool
+		if rpm, ok = rl.keys[key]; !ok {
+			return newErr(http.StatusUnauthorized, codeInvalidAPIKey, "Invalid API key")
+		}
+		id = "key:" + key
+	case rl.cfg.AllowAnonymous:
+		id, rpm = "ip:"+c.RealIP(), rl.cfg.AnonymousRequestsPerMinute
+	default:
+		return newErr(http.StatusUnauthorized, codeMissingAPIKey,
+			"An API key is required, pass it in the "+apiKeyHeader+" header")
+	}
+	if rpm <= 0 {
+		return nil // Unlimited
+	}
+	if cost > rpm {
+		return newErr(http.StatusBadRequest, codeBadRequest,
+			"The request costs more than the rate limit of "+strconv.Itoa(rpm)+" requests per minute")
+	}
+
+	ok, remaining, reset, retry := rl.take(id, rpm, cost, time.Now())
+	h := c.Response().Header()
+	h.Set("X-RateLimit-Limit", strconv.Itoa(rpm))
+	h.Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+	h.Set("X-RateLimit-Reset", strconv.Itoa(int(math.Ceil(reset.Seconds()))))
+	if !ok {
+		ae := newErr(http.StatusTooManyRequests, codeRateLimited, "Rate limit exceeded")
+		ae.RetryAfter = retry
+		return ae
+	}
+	return nil
+}
+
+// take takes cost tokens from the callers bucket, returning whether the
+// request is allowed, the remaining requests, the time until the bucket is
+// full and the time until the request would be allowed
+func (rl *rateLimiter) take(id string, rpm, cost int, now time.Time) (ok bool, remaining int, reset, retry time.Duration) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.sweep(now)
+
+	capacity := float64(rpm)
+	perSecond := capacity / 60
+	b, found := rl.buckets[id]
+	if !found {
+		b = &bucket{tokens: capacity, last: now}
+		rl.buckets[id] = b
+	}
+
+	// Refill the bucket for the time passed since it was last used
+	b.tokens = math.Min(capacity, b.tokens+now.Sub(b.last).Seconds()*perSecond)
+	b.last = now
+
+	if b.tokens >= float64(cost) {
+		b.tokens -= float64(cost)
+		ok = true
+	} else {
+		retry = time.Duration((float64(cost) - b.tokens) / perSecond * float64(time.Second))
+	}
+	reset = time.Duration((capacity - b.tokens) / perSecond * float64(time.Second))
+	return ok, int(b.tokens), reset, retry
+}
+
+// sweep forgets buckets that have been idle long enough to be full again,
+// keeping memory bounded with many anonymous clients
+func (rl *rateLimiter) sweep(now time.Time) {
+	if now.Sub(rl.lastSweep) < time.Minute {
+		return
+	}
+	rl.lastSweep = now
+	for id, b := range rl.buckets {
+		if now.Sub(b.last) > time.Minute {
+			delete(rl.buckets, id)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+// limitedEcho returns an echo serving a single rate limited route
+func limitedEcho(cfg AuthConfig) *echo.Echo {
+	e := echo.New()
+	e.HTTPErrorHandler = newErrorHandler(slog.Default())
+	e.GET("/limited", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	}, newRateLimiter(cfg).middleware)
+	return e
+}
+
+func TestRateLimiter(t *testing.T) {
+	e := limitedEcho(AuthConfig{
+		Keys:                       []APIKey{{Key: "secret", RequestsPerMinute: 3}},
+		AllowAnonymous:             true,
+		AnonymousRequestsPerMinute: 1,
+	})
+	do := func(key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/limited", nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		if key != "" {
+			req.Header.Set(apiKeyHeader, key)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Anonymous clients get the lower quota
+	if rec := do(""); rec.Code != http.StatusOK || rec.Header().Get("X-RateLimit-Limit") != "1" {
+		t.Fatalf("Expected anonymous request to be allowed, got %d %v", rec.Code, rec.Header())
+	}
+	rec := do("")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Fatalf("Expected anonymous request to be limited, got %d %v", rec.Code, rec.Header())
+	}
+	var body apiError
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Code != codeRateLimited {
+		t.Errorf("Expected a %s error body, got %s", codeRateLimited, rec.Body)
+	}
+
+	// Keys are limited separately from their IP
+	for i := 0; i < 3; i++ {
+		if rec := do("secret"); rec.Code != http.StatusOK {
+			t.Fatalf("Expected keyed request %d to be allowed, got %d", i, rec.Code)
+		}
+	}
+	if rec := do("secret"); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected keyed request to be limited, got %d", rec.Code)
+	}
+	if rec := do("wrong"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected invalid key to be rejected, got %d", rec.Code)
+	}
+}
+
+func TestRateLimiterRequireKey(t *testing.T) {
+	e := limitedEcho(AuthConfig{Keys: []APIKey{{Key: "secret"}}})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/limited", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected anonymous request to be rejected, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/limited?api_key=secret", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected request with query key to be allowed, got %d", rec.Code)
+	}
+}
+
+func TestRateLimiterSpoofedIP(t *testing.T) {
+	do := func(e *echo.Echo, remote, xff string) int {
+		req := httptest.NewRequest(http.MethodGet, "/v2/stats/pc/x", nil)
+		req.RemoteAddr = remote + ":1234"
+		req.Header.Set(echo.HeaderXForwardedFor, xff)
+		req.Header.Set(echo.HeaderXRealIP, xff)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// Forwarding headers from clients are ignored by default, so rotating
+	// them doesn't escape the limit
+	cfg := DefaultConfig()
+	cfg.Log.Level = slog.LevelError
+	cfg.Auth.AnonymousRequestsPerMinute = 1
+	e, _ := newService(cfg)
+	if code := do(e, "192.0.2.1", "198.51.100.1"); code == http.StatusTooManyRequests {
+		t.Fatalf("Expected the first request to be allowed, got %d", code)
+	}
+	if code := do(e, "192.0.2.1", "198.51.100.2"); code != http.StatusTooManyRequests {
+		t.Errorf("Expected a spoofed X-Forwarded-For to be limited, got %d", code)
+	}
+
+	// Trusted proxies name the client, anyone else is still limited by their
+	// own address
+	cfg.Server.TrustedProxies = []string{"10.0.0.0/8", "192.0.2.9"}
+	e, _ = newService(cfg)
+	for _, xff := range []string{"198.51.100.1", "198.51.100.2", "203.0.113.1, 198.51.100.3"} {
+		if code := do(e, "10.0.0.1", xff); code == http.StatusTooManyRequests {
+			t.Errorf("Expected %s forwarded by a trusted proxy to be limited separately, got %d", xff, code)
+		}
+	}
+	if code := do(e, "192.0.2.9", "198.51.100.1"); code != http.StatusTooManyRequests {
+		t.Errorf("Expected a client forwarded twice to share its bucket, got %d", code)
+	}
+	do(e, "192.0.2.1", "198.51.100.4")
+	if code := do(e, "192.0.2.1", "198.51.100.5"); code != http.StatusTooManyRequests {
+		t.Errorf("Expected X-Forwarded-For from an untrusted proxy to be ignored, got %d", code)
+	}
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/s32x/ovrstat/internal/flight"
+)
+
+// Statuses of readiness checks
+const (
+	checkOK      = "ok"
+	checkFail    = "fail"
+	checkSkipped = "skipped"
+)
+
+// readiness runs the /readyz checks, reusing the last report for the
+// configured interval
+type readiness struct {
+	cfg    ReadinessConfig
+	flight flight.Group[*readyReport]
+	mu     sync.Mutex
+	last   *readyReport
+}
+
+// readyReport is the JSON breakdown served at /readyz
+type readyReport struct {
+	Status    string                `json:"status"`
+	CheckedAt time.Time             `json:"checkedAt"`
+	Checks    map[string]readyCheck `json:"checks"`
+}
+
+// readyCheck is the result of a single readiness check
+type readyCheck struct {
+	Status   string   `json:"status"`
+	Error    string   `json:"error,omitempty"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// newCheck returns the check result for the passed error
+func newCheck(err error) readyCheck {
+	if err != nil {
+		return readyCheck{Status: checkFail, Error: err.Error()}
+	}
+	return readyCheck{Status: checkOK}
+}
+
+// readyz reports whether the service can serve lookups: upstream is reachable,
+// the parser still finds the expected elements and the cache is writable.
+// Responds 503 if any check fails
+func (h *handler) readyz(c echo.Context) error {
+	r := h.ready.report(c.Request().Context(), h)
+	if r.Status != checkOK {
+		return c.JSON(http.StatusServiceUnavailable, r)
+	}
+	return c.JSON(http.StatusOK, r)
+}
+
+// report returns the last report if it's recent enough, otherwise runs the
+// checks. Concurrent callers wait on a single run, which happens outside the
+// lock so a caller can give up waiting once its context is done. Failing
+// reports are reused too, so a struggling upstream isn't probed by every
+// readiness probe
+func (rd *readiness) report(ctx context.Context, h *handler) *readyReport {
+	rd.mu.Lock()
+	last := rd.last
+	rd.mu.Unlock()
+	if last != nil && time.Since(last.CheckedAt) < rd.cfg.Interval {
+		return last
+	}
+
+	r, _, err := rd.flight.Do(ctx, "readyz", func(ctx context.Context) (*readyReport, error) {
+		r := rd.check(ctx, h)
+		// A run cut short by every caller giving up says nothing about
+		// the service, so it isn't reused
+		if ctx.Err() == nil {
+			rd.mu.Lock()
+			rd.last = r
+			rd.mu.Unlock()
+		}
+		return r, nil
+	})
+	if err != nil {
+		return &readyReport{Status: checkFail, CheckedAt: time.Now(), Checks: map[string]readyCheck{}}
+	}
+	return r
+}
+
+// check runs the readiness checks
+func (rd *readiness) check(ctx context.Context, h *handler) *readyReport {
+	ctx, cancel := context.WithTimeout(ctx, rd.cfg.Timeout)
+	defer cancel()
+	r := &readyReport{Status: checkOK, CheckedAt: time.Now(), Checks: make(map[string]readyCheck)}
+
+	if rd.cfg.CanaryPlatform == "" || rd.cfg.CanaryTag == "" {
+		skipped := readyCheck{Status: checkSkipped}
+		r.Checks["career"], r.Checks["parser"], r.Checks["search"] = skipped, skipped, skipped
+	} else {
+		res := h.client.Probe(ctx, rd.cfg.CanaryPlatform, rd.cfg.CanaryTag)
+		r.Checks["career"] = newCheck(res.Career)
+		r.Checks["search"] = newCheck(res.Search)
+		if res.Career != nil {
+			r.Checks["parser"] = readyCheck{Status: checkSkipped}
+		} else {
+			parser := newCheck(res.Parse)
+			if res.Report != nil {
+				parser.Warnings = res.Report.Warnings()
+			}
+			r.Checks["parser"] = parser
+		}
+	}
+
+	if c, ok := h.cache.(Checker); ok {
+		r.Checks["cache"] = newCheck(c.Check())
+	} else {
+		r.Checks["cache"] = readyCheck{Status: checkSkipped}
+	}
+
+	for _, check := range r.Checks {
+		if check.Status == checkFail {
+			r.Status = checkFail
+		}
+	}
+	return r
+}
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestReadyz(t *testing.T) {
+	career := "pc-public.html"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		testdata := filepath.Join("..", "ovrstat", "testdata")
+		if strings.HasPrefix(r.URL.Path, "/search/") {
+			http.ServeFile(w, r, filepath.Join(testdata, "search", "viz.json"))
+			return
+		}
+		http.ServeFile(w, r, filepath.Join(testdata, "career", career))
+	}))
+	defer srv.Close()
+
+	cfg := DefaultConfig()
+	cfg.Upstream.BaseURL = srv.URL + "/career"
+	cfg.Upstream.APIURL = srv.URL + "/search/"
+	cfg.Readiness.CanaryPlatform = "pc"
+	cfg.Readiness.CanaryTag = "Viz-1213"
+	cfg.Readiness.Interval = 0
+	cfg.Log.Level = slog.LevelError
+	e, _ := newService(cfg)
+
+	ready := func() (int, readyReport) {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		var r readyReport
+		if err := json.Unmarshal(rec.Body.Bytes(), &r); err != nil {
+			t.Fatal(err)
+		}
+		return rec.Code, r
+	}
+
+	code, r := ready()
+	if code != http.StatusOK || r.Status != checkOK {
+		t.Fatalf("Expected the service to be ready, got %d %+v", code, r)
+	}
+	for _, name := range []string{"career", "parser", "search", "cache"} {
+		if r.Checks[name].Status != checkOK {
+			t.Errorf("Expected the %s check to pass, got %+v", name, r.Checks[name])
+		}
+	}
+
+	// A broken parser takes the service out of rotation
+	career = "redesigned.html"
+	code, r = ready()
+	if code != http.StatusServiceUnavailable || r.Checks["parser"].Status != checkFail ||
+		r.Checks["career"].Status != checkOK {
+		t.Errorf("Expected only the parser check to fail, got %d %+v", code, r)
+	}
+}
+
+func TestReadyzWithoutCanary(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	cfg := DefaultConfig()
+	cfg.Upstream.BaseURL = srv.URL + "/career"
+	cfg.Upstream.APIURL = srv.URL + "/search/"
+	cfg.Log.Level = slog.LevelError
+	e, _ := newService(cfg)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	var r readyReport
+	if err := json.Unmarshal(rec.Body.Bytes(), &r); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusOK || r.Status != checkOK || requests != 0 {
+		t.Fatalf("Expected the service to be ready without contacting upstream, got %d %+v after %d requests", rec.Code, r, requests)
+	}
+	for _, name := range []string{"career", "parser", "search"} {
+		if r.Checks[name].Status != checkSkipped {
+			t.Errorf("Expected the %s check to be skipped, got %+v", name, r.Checks[name])
+		}
+	}
+	if r.Checks["cache"].Status != checkOK {
+		t.Errorf("Expected the cache check to pass, got %+v", r.Checks["cache"])
+	}
+}
+
+func TestReadyzReuse(t *testing.T) {
+	var requests atomic.Int32
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		<-release
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	cfg := DefaultConfig()
+	cfg.Upstream.BaseURL = srv.URL + "/career"
+	cfg.Upstream.APIURL = srv.URL + "/search/"
+	cfg.Readiness.CanaryPlatform = "pc"
+	cfg.Readiness.CanaryTag = "Viz-1213"
+	cfg.Log.Level = slog.LevelError
+	cfg.Cache.Store = NewMemoryCache(1)
+	cfg.Cache.Store.Set("pc/Viz-1213", &CacheEntry{StoredAt: time.Now()})
+	_, h := newService(cfg)
+
+	// A caller stops waiting on a slow run once its context is done
+	go h.ready.report(context.Background(), h)
+	for requests.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if r := h.ready.report(ctx, h); r.Status != checkFail || time.Since(start) > time.Second {
+		t.Errorf("Expected a failing report once the caller gave up, got %+v after %s", r, time.Since(start))
+	}
+	close(release)
+
+	// The failing report of the run is reused for the interval
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		h.ready.mu.Lock()
+		last := h.ready.last
+		h.ready.mu.Unlock()
+		if last != nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("The readiness run never completed")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	n := requests.Load()
+	r := h.ready.report(context.Background(), h)
+	if r.Status != checkFail || r.Checks["career"].Status != checkFail || requests.Load() != n {
+		t.Errorf("Expected the failing report to be reused, got %+v after %d requests", r, requests.Load()-n)
+	}
+
+	// Checking the cache doesn't store an entry in it
+	if r.Checks["cache"].Status != checkOK {
+		t.Errorf("Expected the cache check to pass, got %+v", r.Checks["cache"])
+	}
+	if _, ok := cfg.Cache.Store.Get("pc/Viz-1213"); !ok {
+		t.Error("Expected the cache check not to evict cached stats")
+	}
+}
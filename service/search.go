@@ -0,0 +1,90 @@
+package service
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/s32x/ovrstat/ovrstat"
+)
+
+// Search pagination defaults and limits
+const (
+	defaultPerPage = 20
+	maxPerPage     = 100
+)
+
+// searchResults is a page of accounts matching a search
+type searchResults struct {
+	Query    string             `json:"query"`
+	Platform string             `json:"platform,omitempty"`
+	Page     int                `json:"page"`
+	PerPage  int                `json:"perPage"`
+	Total    int                `json:"total"`
+	Results  []ovrstat.Platform `json:"results"`
+}
+
+// search handles searching for accounts by name, optionally filtered to a
+// single platform and paginated with the page and perPage query parameters
+func (h *handler) search(c echo.Context) error {
+	r := searchResults{
+		Query:    c.QueryParam("q"),
+		Platform: c.QueryParam("platform"),
+		Page:     1,
+		PerPage:  defaultPerPage,
+	}
+	switch r.Platform {
+	case "", ovrstat.PlatformPC, ovrstat.PlatformPSN, ovrstat.PlatformXBL, ovrstat.PlatformNS:
+	default:
+		return statsErr(ovrstat.ErrInvalidPlatform)
+	}
+	if err := intParam(c, "page", &r.Page, 1, 0); err != nil {
+		return err
+	}
+	if err := intParam(c, "perPage", &r.PerPage, 1, maxPerPage); err != nil {
+		return err
+	}
+
+	accounts, err := h.client.Search(c.Request().Context(), r.Query)
+	if err != nil {
+		return h.apiErr(err)
+	}
+
+	// Pages too far out to hold any results are served empty rather than
+	// overflowing their offset
+	offset := math.MaxInt
+	if r.Page-1 <= math.MaxInt/r.PerPage {
+		offset = (r.Page - 1) * r.PerPage
+	}
+	r.Results = make([]ovrstat.Platform, 0, r.PerPage)
+	for _, a := range accounts {
+		if r.Platform != "" && a.Platform != r.Platform {
+			continue
+		}
+		if r.Total >= offset && len(r.Results) < r.PerPage {
+			r.Results = append(r.Results, a)
+		}
+		r.Total++
+	}
+	return c.JSON(http.StatusOK, r)
+}
+
+// intParam reads an optional integer query parameter into v, which must be at
+// least min and, if max is positive, at most max
+func intParam(c echo.Context, name string, v *int, min, max int) error {
+	s := c.QueryParam(name)
+	if s == "" {
+		return nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < min || (max > 0 && n > max) {
+		msg := "Invalid " + name + ", expected an integer of at least " + strconv.Itoa(min)
+		if max > 0 {
+			msg += " and at most " + strconv.Itoa(max)
+		}
+		return newErr(http.StatusBadRequest, codeBadRequest, msg)
+	}
+	*v = n
+	return nil
+}
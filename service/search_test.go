@@ -0,0 +1,56 @@
+package service
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestSearch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, filepath.Join("..", "ovrstat", "testdata", "search", "tayuya.json"))
+	}))
+	defer srv.Close()
+
+	cfg := DefaultConfig()
+	cfg.Upstream.APIURL = srv.URL + "/search/"
+	cfg.Log.Level = slog.LevelError
+	e, _ := newService(cfg)
+	search := func(query string) (int, searchResults) {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v2/search?"+query, nil))
+		var r searchResults
+		json.Unmarshal(rec.Body.Bytes(), &r)
+		return rec.Code, r
+	}
+
+	code, r := search("q=TayuyaBreast")
+	if code != http.StatusOK || r.Total != 3 || len(r.Results) != 3 {
+		t.Errorf("Expected every account, got %d %+v", code, r)
+	}
+
+	// Results are filtered before they're paginated
+	code, r = search("q=TayuyaBreast&platform=psn&perPage=1&page=2")
+	if code != http.StatusOK || r.Total != 2 || len(r.Results) != 1 || r.Results[0].ID != 3002 {
+		t.Errorf("Expected the second psn account, got %d %+v", code, r)
+	}
+	code, r = search("q=TayuyaBreast&page=3&perPage=2")
+	if code != http.StatusOK || r.Total != 3 || len(r.Results) != 0 {
+		t.Errorf("Expected an empty page past the results, got %d %+v", code, r)
+	}
+	for _, page := range []string{"4611686018427387905", "9223372036854775807"} {
+		code, r = search("q=TayuyaBreast&perPage=2&page=" + page)
+		if code != http.StatusOK || r.Total != 3 || len(r.Results) != 0 {
+			t.Errorf("Expected page %s to be empty, got %d %+v", page, code, r)
+		}
+	}
+
+	for _, query := range []string{"q=", "q=a&platform=wii", "q=a&page=0", "q=a&perPage=1000", "q=a&page=x"} {
+		if code, _ := search(query); code != http.StatusBadRequest {
+			t.Errorf("Expected %q to be rejected, got %d", query, code)
+		}
+	}
+}
@@ -1,11 +1,16 @@
 package service
 
 import (
+	"context"
 	"embed"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
+	"github.com/s32x/ovrstat/ovrstat"
 )
 
 //go:embed static/*
@@ -13,36 +18,127 @@ var staticFS embed.FS
 
 // Start starts serving the service on the passed port
 func Start(port string) {
-	e := Echo()
-	// Listen on the specified port
-	e.Logger.Fatal(e.Start(":" + port))
+	cfg := DefaultConfig()
+	cfg.Addr = ":" + port
+	StartWithConfig(cfg)
+}
+
+// StartWithConfig starts serving the service on the configured address using
+// the passed config, shutting down gracefully on SIGINT or SIGTERM
+func StartWithConfig(cfg Config) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := Run(ctx, cfg); err != nil {
+		cfg.Log.newLogger().Error("server stopped", "error", err)
+		os.Exit(1)
+	}
 }
 
 // Echo creates and returns a new echo Echo for the service
 func Echo() *echo.Echo {
+	return EchoWithConfig(DefaultConfig())
+}
+
+// EchoWithConfig creates and returns a new echo Echo for the service using the
+// passed config
+func EchoWithConfig(cfg Config) *echo.Echo {
+	e, _ := newService(cfg)
+	return e
+}
+
+// newService creates the echo Echo for the service along with the handler
+// serving it
+func newService(cfg Config) (*echo.Echo, *handler) {
 	// Create a new echo Echo and bind all middleware
 	e := echo.New()
 	e.HideBanner = true
+	e.HidePort = true
+	e.Server.ReadTimeout = cfg.Server.ReadTimeout
+	e.Server.ReadHeaderTimeout = cfg.Server.ReadHeaderTimeout
+	e.Server.WriteTimeout = cfg.Server.WriteTimeout
+	e.Server.IdleTimeout = cfg.Server.IdleTimeout
+	logger := cfg.Log.newLogger()
+	e.HTTPErrorHandler = newErrorHandler(logger)
+	e.IPExtractor, _ = cfg.Server.ipExtractor() // Validated by Load
+
+	h := &handler{
+		cache:    cfg.Cache.newCache(logger),
+		cacheCfg: cfg.Cache,
+		limiter:  cfg.Upstream.newLimiter(),
+		breaker:  cfg.Upstream.newBreaker(),
+		logger:   logger,
+		ready:    &readiness{cfg: cfg.Readiness},
+
+		rateLimit:         newRateLimiter(cfg.Auth),
+		batchCfg:          cfg.Batch,
+		canonicalRedirect: cfg.CanonicalRedirect,
+	}
+	h.metrics = newMetrics(h)
+	opts := []ovrstat.Option{
+		ovrstat.WithHTTPClient(&http.Client{Timeout: cfg.Upstream.Timeout}),
+		ovrstat.WithHooks(h.metrics.hooks()),
+		ovrstat.WithHooks(ovrstat.Hooks{Lookup: h.lookedUp}),
+		ovrstat.WithLogger(logger),
+	}
+	if cfg.AccountResolution != "" {
+		opts = append(opts, ovrstat.WithResolution(cfg.AccountResolution))
+	}
+	if cfg.CaseInsensitiveTags {
+		opts = append(opts, ovrstat.WithCaseInsensitiveTags())
+	}
+	if cfg.Upstream.BaseURL != "" {
+		opts = append(opts, ovrstat.WithBaseURL(cfg.Upstream.BaseURL))
+	}
+	if cfg.Upstream.APIURL != "" {
+		opts = append(opts, ovrstat.WithAPIURL(cfg.Upstream.APIURL))
+	}
+	if h.limiter != nil {
+		opts = append(opts, ovrstat.WithRateLimiter(h.limiter))
+	}
+	if h.breaker != nil {
+		opts = append(opts, ovrstat.WithCircuitBreaker(h.breaker))
+	}
+	h.client = ovrstat.NewClient(opts...)
 
 	// Bind middleware
 	e.Pre(middleware.RemoveTrailingSlashWithConfig(
 		middleware.TrailingSlashConfig{
 			RedirectCode: http.StatusPermanentRedirect,
 		}))
-	e.Use(middleware.Logger())
+	e.Use(requestID())
+	e.Use(accessLog(logger))
+	e.Use(h.metrics.middleware)
 	e.Use(middleware.Recover())
 	e.Pre(middleware.Secure())
 	e.Use(middleware.Gzip())
-	e.Use(middleware.CORS())
+	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
+		AllowOrigins: cfg.CORS.AllowOrigins,
+	}))
 
 	// Serve the static web content on the base echo instance
 	e.GET("/*", echo.WrapHandler(http.FileServer(http.FS(staticFS))),
 		middleware.Rewrite(map[string]string{"/*": "/static/$1"}))
 
 	// Handle stats API requests
-	e.GET("/stats/:platform/:tag", stats)
-	e.GET("/healthcheck", func(c echo.Context) error {
-		return c.NoContent(http.StatusOK)
-	})
-	return e
+	// The unversioned routes alias v1
+	// Batches are charged for every player once their body is read
+	limit := h.rateLimit.middleware
+	e.GET("/stats/:platform/:tag", h.stats, cfg.API.deprecateV1, limit)
+	e.GET("/v1/stats/:platform/:tag", h.stats, cfg.API.deprecateV1, limit)
+	e.GET("/v2/stats/:platform/:tag", h.statsV2, limit)
+	batchLimit := middleware.BodyLimit("64K")
+	e.POST("/stats/batch", h.batch, cfg.API.deprecateV1, batchLimit)
+	e.POST("/v1/stats/batch", h.batch, cfg.API.deprecateV1, batchLimit)
+	e.POST("/v2/stats/batch", h.batchV2, batchLimit)
+	e.GET("/search", h.search, cfg.API.deprecateV1, limit)
+	e.GET("/v1/search", h.search, cfg.API.deprecateV1, limit)
+	e.GET("/v2/search", h.search, limit)
+	e.GET("/status", h.status)
+	e.GET("/openapi.json", openAPIHandler())
+	if cfg.Metrics {
+		e.GET("/metrics", h.metrics.handler())
+	}
+	e.GET("/healthcheck", h.healthcheck)
+	e.GET("/readyz", h.readyz)
+	return e, h
 }
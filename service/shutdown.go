@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+)
+
+// Run serves the service on the configured address until ctx is cancelled,
+// then shuts down gracefully. /healthcheck reports unhealthy for the
+// configured drain delay before the server stops accepting connections, after
+// which in-flight requests and background refreshes are waited on for up to
+// the shutdown timeout and the cache is flushed
+func Run(ctx context.Context, cfg Config) error {
+	e, h := newService(cfg)
+	h.logger.Info("starting server", "addr", cfg.Addr)
+
+	errc := make(chan error, 1)
+	go func() { errc <- e.Start(cfg.Addr) }()
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	h.logger.Info("draining server", "drainDelay", cfg.Server.DrainDelay,
+		"shutdownTimeout", cfg.Server.ShutdownTimeout)
+	h.draining.Store(true)
+	time.Sleep(cfg.Server.DrainDelay)
+
+	// The cache is flushed even if requests fail to drain, so a slow request
+	// never costs the snapshot
+	sctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	drainErr := e.Shutdown(sctx)
+	if drainErr != nil {
+		drainErr = errors.Wrap(drainErr, "Failed to drain in-flight requests")
+		h.logger.Error("failed to drain server", "error", drainErr)
+	}
+	if err := <-errc; err != nil && err != http.ErrServerClosed {
+		return err
+	}
+	if err := h.shutdown(sctx); err != nil {
+		return err
+	}
+	if drainErr != nil {
+		return drainErr
+	}
+	h.logger.Info("server stopped")
+	return nil
+}
+
+// healthcheck reports whether the service is accepting requests, failing once
+// it begins shutting down
+func (h *handler) healthcheck(c echo.Context) error {
+	if h.draining.Load() {
+		return c.NoContent(http.StatusServiceUnavailable)
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+// shutdown waits for background refreshes to complete, then flushes the cache.
+// The cache is flushed even if ctx is done before the refreshes complete
+func (h *handler) shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		h.refreshes.Wait()
+		close(done)
+	}()
+	var waitErr error
+	select {
+	case <-done:
+	case <-ctx.Done():
+		waitErr = errors.Wrap(ctx.Err(), "Failed to complete background refreshes")
+	}
+
+	if f, ok := h.cache.(Flusher); ok {
+		if err := f.Flush(); err != nil {
+			return errors.Wrap(err, "Failed to flush cache")
+		}
+	}
+	return waitErr
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRunShutdown(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	cfg := DefaultConfig()
+	cfg.Addr = addr
+	cfg.Cache.Snapshot = filepath.Join(t.TempDir(), "cache.json")
+	cfg.Server.DrainDelay = 300 * time.Millisecond
+	cfg.Log.Level = slog.LevelError
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- Run(ctx, cfg) }()
+
+	health := func() int {
+		res, err := http.Get("http://" + addr + "/healthcheck")
+		if err != nil {
+			return 0
+		}
+		res.Body.Close()
+		return res.StatusCode
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for health() != http.StatusOK {
+		if time.Now().After(deadline) {
+			t.Fatal("Server never became healthy")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// The healthcheck fails as soon as draining begins
+	cancel()
+	time.Sleep(100 * time.Millisecond)
+	if status := health(); status != http.StatusServiceUnavailable {
+		t.Errorf("Expected the healthcheck to fail while draining, got %d", status)
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Server did not shut down")
+	}
+	if _, err := os.Stat(cfg.Cache.Snapshot); err != nil {
+		t.Errorf("Expected the cache to be flushed on shutdown, got %v", err)
+	}
+}
+
+func TestRunShutdownTimeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	cfg := DefaultConfig()
+	cfg.Addr = addr
+	cfg.Upstream.BaseURL = srv.URL + "/career"
+	cfg.Upstream.APIURL = srv.URL + "/search/"
+	cfg.Cache.Snapshot = filepath.Join(t.TempDir(), "cache.json")
+	cfg.Server.DrainDelay = 0
+	cfg.Server.ShutdownTimeout = 100 * time.Millisecond
+	cfg.Log.Level = slog.LevelError + 1
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- Run(ctx, cfg) }()
+
+	// Start a lookup that outlives the shutdown timeout
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		conn, err := net.Dial("tcp", addr)
+		if err == nil {
+			fmt.Fprintf(conn, "GET /v2/stats/pc/Viz-1213 HTTP/1.1\r\nHost: %s\r\n\r\n", addr)
+			defer conn.Close()
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Server never started")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("Expected an error draining the in-flight lookup")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Server did not shut down")
+	}
+	if _, err := os.Stat(cfg.Cache.Snapshot); err != nil {
+		t.Errorf("Expected the cache to be flushed despite the timeout, got %v", err)
+	}
+}
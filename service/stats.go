@@ -1,23 +1,224 @@
 package service
 
 import (
+	"context"
+	"log/slog"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/pkg/errors"
 	"github.com/s32x/ovrstat/ovrstat"
+	"golang.org/x/time/rate"
 )
 
-// stats handles retrieving and serving Overwatch stats in JSON
-func stats(c echo.Context) error {
-	// Perform a full player stats lookup
-	stats, err := ovrstat.Stats(c.Param("platform"), c.Param("tag"))
+// Cache statuses reported in the X-Cache response header
+const (
+	cacheHit   = "HIT"
+	cacheMiss  = "MISS"
+	cacheStale = "STALE"
+)
+
+// handler serves stats lookups, caching them when a Cache is configured
+type handler struct {
+	client     *ovrstat.Client
+	cache      Cache
+	cacheCfg   CacheConfig
+	limiter    *rate.Limiter
+	breaker    *ovrstat.CircuitBreaker
+	metrics    *metrics
+	logger     *slog.Logger
+	ready      *readiness
+	refreshing sync.Map       // Keys with a background refresh in flight
+	refreshes  sync.WaitGroup // Background refreshes, waited on at shutdown
+	draining   atomic.Bool    // Set once the service begins shutting down
+
+	rateLimit         *rateLimiter
+	batchCfg          BatchConfig
+	canonicalRedirect bool // Redirect lookups to the URL of the canonical tag
+}
+
+// serveStats looks up the requested player and serves their stats in the
+// schema returned by render. Lookups of a tag that isn't canonical, e.g. in
+// the wrong case, name the canonical URL in the Content-Location header or
+// are redirected to it
+func (h *handler) serveStats(c echo.Context, render func(*ovrstat.PlayerStats) interface{}) error {
+	stats, err := h.playerStats(c)
 	if err != nil {
-		if err == ovrstat.ErrPlayerNotFound {
-			return newErr(http.StatusNotFound, "Player not found")
+		return err
+	}
+	if loc := canonicalURL(c.Request().URL, stats.Tag); loc != "" {
+		if h.canonicalRedirect {
+			return c.Redirect(http.StatusMovedPermanently, loc)
+		}
+		c.Response().Header().Set("Content-Location", loc)
+	}
+	return c.JSON(http.StatusOK, render(stats))
+}
+
+// canonicalURL returns the URL of a stats request with the tag replaced by the
+// passed canonical tag, or an empty string if the request already uses it
+func canonicalURL(u *url.URL, tag string) string {
+	path := u.EscapedPath()
+	i := strings.LastIndex(path, "/")
+	if tag == "" || i < 0 {
+		return ""
+	}
+	canonical := path[:i+1] + url.PathEscape(tag)
+	if canonical == path {
+		return ""
+	}
+	if u.RawQuery != "" {
+		canonical += "?" + u.RawQuery
+	}
+	return canonical
+}
+
+// playerStats performs a full stats lookup of the requested player, or of the
+// account chosen by the id query parameter, returning any error as the
+// apiError to serve. Invalid tags are rejected without contacting upstream
+func (h *handler) playerStats(c echo.Context) (*ovrstat.PlayerStats, error) {
+	var id int
+	if err := intParam(c, "id", &id, 1, 0); err != nil {
+		return nil, err
+	}
+	pid, err := ovrstat.ParsePlayerID(c.Param("platform"), c.Param("tag"))
+	if err != nil {
+		return nil, h.apiErr(err)
+	}
+	stats, err := h.lookup(c.Request().Context(), c.Response().Header(), pid, id)
+	if err != nil {
+		return nil, h.apiErr(err)
+	}
+	return stats, nil
+}
+
+// apiErr maps an error returned by the client to the apiError to serve,
+// telling clients when to retry while the circuit breaker is open
+func (h *handler) apiErr(err error) *apiError {
+	ae := statsErr(err)
+	if errors.Is(err, ovrstat.ErrCircuitOpen) {
+		ae.RetryAfter = h.breaker.RetryIn()
+	}
+	return ae
+}
+
+// lookup retrieves stats from the cache if present and fresh, otherwise from
+// upstream. Expired entries within the stale-while-revalidate window are
+// served immediately while a background refresh updates the cache. The cache
+// result is reported in hdr unless nil
+func (h *handler) lookup(ctx context.Context, hdr http.Header, pid ovrstat.PlayerID, id int) (*ovrstat.PlayerStats, error) {
+	if h.cache == nil {
+		return h.fetch(ctx, pid, id)
+	}
+	key := cacheKey(pid, id)
+
+	entry, ok := h.cache.Get(key)
+	if ok {
+		age := entry.Age(time.Now())
+		ttl := h.cacheCfg.ttl(pid.Platform)
+		switch {
+		case age < ttl:
+			h.cacheResult(hdr, cacheHit, age)
+			return entry.Stats, nil
+		case age < ttl+h.cacheCfg.StaleWhileRevalidate:
+			h.revalidate(ctx, key, pid, id)
+			h.cacheResult(hdr, cacheStale, age)
+			return entry.Stats, nil
 		}
-		return newErr(http.StatusInternalServerError,
-			errors.Wrap(err, "Failed to retrieve player stats"))
 	}
-	return c.JSON(http.StatusOK, stats)
+
+	stats, err := h.fetch(ctx, pid, id)
+	if err != nil {
+		// Serve whatever is cached, however old, while upstream requests are
+		// paused by the circuit breaker
+		if ok && errors.Is(err, ovrstat.ErrCircuitOpen) {
+			h.cacheResult(hdr, cacheStale, entry.Age(time.Now()))
+			return entry.Stats, nil
+		}
+		return nil, err
+	}
+	h.cacheResult(hdr, cacheMiss, 0)
+	return stats, nil
+}
+
+// fetch retrieves stats from upstream. Concurrent fetches of the same player
+// are coalesced by the client, which also stores the result in the cache
+// through lookedUp
+func (h *handler) fetch(ctx context.Context, pid ovrstat.PlayerID, id int) (*ovrstat.PlayerStats, error) {
+	return h.client.StatsByID(ctx, pid.Platform, pid.URLName(), id)
+}
+
+// lookedUp logs a completed upstream lookup and stores its stats in the cache.
+// Called by the client once per upstream fetch, however many callers share it
+func (h *handler) lookedUp(ctx context.Context, ev ovrstat.LookupEvent) {
+	key := cacheKey(ev.Player, ev.ID)
+	if ev.Err != nil {
+		if !errors.Is(ev.Err, ovrstat.ErrPlayerNotFound) && !errors.Is(ev.Err, ovrstat.ErrInvalidPlatform) &&
+			!errors.Is(ev.Err, ovrstat.ErrAmbiguousPlayer) {
+			h.logger.WarnContext(ctx, "stats lookup failed", "key", key, "error", ev.Err)
+		}
+		return
+	}
+	if ev.Stats.Report != nil && len(ev.Stats.Report.Warnings()) > 0 {
+		h.logger.WarnContext(ctx, "career page is missing optional elements",
+			"key", key, "missing", ev.Stats.Report.Warnings())
+	}
+	if h.cache != nil {
+		h.store(ctx, key, ev.Stats)
+	}
+}
+
+// cacheKey returns the cache key of a lookup
+func cacheKey(pid ovrstat.PlayerID, id int) string {
+	key := pid.Platform + "/" + pid.URLName()
+	if id != 0 {
+		key += "#" + strconv.Itoa(id)
+	}
+	return key
+}
+
+// revalidate refreshes a cache entry in the background, ensuring only a single
+// refresh per key is in flight at a time. The refresh carries the request ID
+// of the request that triggered it
+func (h *handler) revalidate(ctx context.Context, key string, pid ovrstat.PlayerID, id int) {
+	reqID := ovrstat.RequestID(ctx)
+	if _, loaded := h.refreshing.LoadOrStore(key, struct{}{}); loaded {
+		return
+	}
+	h.refreshes.Add(1)
+	go func() {
+		defer h.refreshes.Done()
+		defer h.refreshing.Delete(key)
+		ctx, cancel := context.WithTimeout(
+			ovrstat.WithRequestID(context.Background(), reqID), h.cacheCfg.RefreshTimeout)
+		defer cancel()
+
+		if _, err := h.fetch(ctx, pid, id); err != nil {
+			h.logger.ErrorContext(ctx, "failed to refresh cached stats", "key", key, "error", err)
+		}
+	}()
+}
+
+// store writes freshly retrieved stats to the cache
+func (h *handler) store(ctx context.Context, key string, stats *ovrstat.PlayerStats) {
+	entry := &CacheEntry{Stats: stats, StoredAt: time.Now()}
+	if err := h.cache.Set(key, entry); err != nil {
+		h.logger.ErrorContext(ctx, "failed to cache stats", "key", key, "error", err)
+	}
+}
+
+// cacheResult records the result of a cache lookup and sets the X-Cache and
+// Age headers in hdr unless nil
+func (h *handler) cacheResult(hdr http.Header, status string, age time.Duration) {
+	h.metrics.cacheLookups.WithLabelValues(strings.ToLower(status)).Inc()
+	if hdr != nil {
+		hdr.Set("X-Cache", status)
+		hdr.Set("Age", strconv.Itoa(int(age.Seconds())))
+	}
 }
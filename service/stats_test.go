@@ -0,0 +1,124 @@
+package service
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStatsCanonicalTag(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/career/pc/Viz-1213":
+			http.ServeFile(w, r, filepath.Join("..", "ovrstat", "testdata", "career", "pc-public.html"))
+		case strings.HasPrefix(r.URL.Path, "/search/"):
+			http.ServeFile(w, r, filepath.Join("..", "ovrstat", "testdata", "search", "viz.json"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	cfg := DefaultConfig()
+	cfg.Upstream.BaseURL = srv.URL + "/career"
+	cfg.Upstream.APIURL = srv.URL + "/search/"
+	cfg.Log.Level = slog.LevelError
+	cfg.CaseInsensitiveTags = true
+	e, _ := newService(cfg)
+	get := func(e http.Handler, target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+
+	rec := get(e, "/v2/stats/pc/viz-1213?id=1001")
+	var stats playerStatsV2
+	json.Unmarshal(rec.Body.Bytes(), &stats)
+	if rec.Code != http.StatusOK || stats.Tag != "Viz-1213" {
+		t.Errorf("Expected the stats of Viz-1213, got %d %+v", rec.Code, stats)
+	}
+	if loc := rec.Header().Get("Content-Location"); loc != "/v2/stats/pc/Viz-1213?id=1001" {
+		t.Errorf("Expected the canonical Content-Location, got %q", loc)
+	}
+	if rec := get(e, "/v2/stats/pc/Viz-1213"); rec.Header().Get("Content-Location") != "" {
+		t.Errorf("Expected no Content-Location for the canonical tag, got %q", rec.Header().Get("Content-Location"))
+	}
+
+	cfg.CanonicalRedirect = true
+	e, _ = newService(cfg)
+	rec = get(e, "/stats/pc/viz%231213")
+	if rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != "/stats/pc/Viz-1213" {
+		t.Errorf("Expected a redirect to /stats/pc/Viz-1213, got %d %q", rec.Code, rec.Header().Get("Location"))
+	}
+}
+
+func TestStatsCoalescing(t *testing.T) {
+	var careers atomic.Int32
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/search/") {
+			http.ServeFile(w, r, filepath.Join("..", "ovrstat", "testdata", "search", "viz.json"))
+			return
+		}
+		careers.Add(1)
+		<-release
+		http.ServeFile(w, r, filepath.Join("..", "ovrstat", "testdata", "career", "pc-public.html"))
+	}))
+	defer srv.Close()
+
+	cfg := DefaultConfig()
+	cfg.Upstream.BaseURL = srv.URL + "/career"
+	cfg.Upstream.APIURL = srv.URL + "/search/"
+	cfg.Log.Level = slog.LevelError
+	e, h := newService(cfg)
+
+	// Concurrent requests for a player share a single upstream lookup, which
+	// is recorded and cached once
+	const n = 5
+	var wg sync.WaitGroup
+	codes := make(chan int, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v2/stats/pc/Viz-1213", nil))
+			codes <- rec.Code
+		}()
+	}
+	for h.client.CoalesceStats().Calls < n {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+	close(codes)
+
+	for code := range codes {
+		if code != http.StatusOK {
+			t.Errorf("Expected every request to succeed, got %d", code)
+		}
+	}
+	if careers.Load() != 1 || h.client.CoalesceStats().Coalesced != n-1 {
+		t.Errorf("Expected a single upstream lookup, got %d with stats %+v", careers.Load(), h.client.CoalesceStats())
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	for _, metric := range []string{
+		`ovrstat_lookups_total{platform="pc",result="ok"} 1`,
+		`ovrstat_coalesced_lookups_total 4`,
+	} {
+		if !strings.Contains(rec.Body.String(), metric+"\n") {
+			t.Errorf("Expected the metrics to report %s", metric)
+		}
+	}
+	if _, ok := h.cache.Get("pc/Viz-1213"); !ok {
+		t.Error("Expected the shared lookup to be cached")
+	}
+}
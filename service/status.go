@@ -0,0 +1,40 @@
+package service
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/s32x/ovrstat/ovrstat"
+)
+
+// status is the state of the services upstream protections
+type status struct {
+	Breaker    *ovrstat.BreakerStatus `json:"breaker,omitempty"`
+	RateLimit  *rateLimitStatus       `json:"rateLimit,omitempty"`
+	Coalescing ovrstat.CoalesceStats  `json:"coalescing"`
+}
+
+// rateLimitStatus is the configuration of the outbound rate limiter
+type rateLimitStatus struct {
+	PerSecond float64 `json:"perSecond"`
+	Burst     int     `json:"burst"`
+}
+
+// status serves the state of the circuit breaker, rate limiter and lookup
+// coalescing
+func (h *handler) status(c echo.Context) error {
+	s := status{
+		Coalescing: h.client.CoalesceStats(),
+	}
+	if h.breaker != nil {
+		bs := h.breaker.Status()
+		s.Breaker = &bs
+	}
+	if h.limiter != nil {
+		s.RateLimit = &rateLimitStatus{
+			PerSecond: float64(h.limiter.Limit()),
+			Burst:     h.limiter.Burst(),
+		}
+	}
+	return c.JSON(http.StatusOK, s)
+}
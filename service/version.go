@@ -0,0 +1,279 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/s32x/ovrstat/ovrstat"
+)
+
+// playerStatsV1 is the v1 schema of a players stats. It is frozen in the shape
+// served before versioning, so changes to ovrstat.PlayerStats never reach v1
+// clients
+type playerStatsV1 struct {
+	Icon             string             `json:"icon"`
+	Name             string             `json:"name"`
+	Level            int                `json:"level"`
+	LevelIcon        string             `json:"levelIcon"`
+	Endorsement      int                `json:"endorsement"`
+	EndorsementIcon  string             `json:"endorsementIcon"`
+	Prestige         int                `json:"prestige"`
+	PrestigeIcon     string             `json:"prestigeIcon"`
+	Ratings          []ratingV1         `json:"ratings"`
+	GamesWon         int                `json:"gamesWon"`
+	QuickPlayStats   quickPlayStatsV1   `json:"quickPlayStats"`
+	CompetitiveStats competitiveStatsV1 `json:"competitiveStats"`
+	Private          bool               `json:"private"`
+}
+
+// ratingV1 is a players competitive rating in a role
+type ratingV1 struct {
+	Level    int    `json:"level"`
+	Role     string `json:"role"`
+	RoleIcon string `json:"roleIcon"`
+	RankIcon string `json:"rankIcon"`
+}
+
+// statsCollectionV1 holds the stats of a single game mode, keyed by hero
+type statsCollectionV1 struct {
+	TopHeroes   map[string]*topHeroV1     `json:"topHeroes"`
+	CareerStats map[string]*careerStatsV1 `json:"CareerStats"`
+}
+
+// quickPlayStatsV1 holds the quick play stats
+type quickPlayStatsV1 struct {
+	statsCollectionV1
+}
+
+// competitiveStatsV1 holds the competitive stats of the current season
+type competitiveStatsV1 struct {
+	Season *int `json:"season"`
+	statsCollectionV1
+}
+
+// topHeroV1 holds the basic stats of a hero, with the time played as shown on
+// the career page
+type topHeroV1 struct {
+	TimePlayed          string  `json:"timePlayed"`
+	GamesWon            int     `json:"gamesWon"`
+	WinPercentage       int     `json:"winPercentage"`
+	WeaponAccuracy      int     `json:"weaponAccuracy"`
+	EliminationsPerLife float64 `json:"eliminationsPerLife"`
+	MultiKillBest       int     `json:"multiKillBest"`
+	ObjectiveKills      float64 `json:"objectiveKills"`
+}
+
+// careerStatsV1 holds the detailed stats of a hero as untyped values by
+// category
+type careerStatsV1 struct {
+	Assists       map[string]interface{} `json:"assists"`
+	Average       map[string]interface{} `json:"average"`
+	Best          map[string]interface{} `json:"best"`
+	Combat        map[string]interface{} `json:"combat"`
+	Deaths        map[string]interface{} `json:"deaths"`
+	HeroSpecific  map[string]interface{} `json:"heroSpecific"`
+	Game          map[string]interface{} `json:"game"`
+	MatchAwards   map[string]interface{} `json:"matchAwards"`
+	Miscellaneous map[string]interface{} `json:"miscellaneous"`
+}
+
+// newPlayerStatsV1 converts stats to the v1 schema, serving career stats in
+// their legacy untyped form
+func newPlayerStatsV1(ps *ovrstat.PlayerStats) *playerStatsV1 {
+	v := &playerStatsV1{
+		Icon:             ps.Icon,
+		Name:             ps.Name,
+		Level:            ps.Level,
+		LevelIcon:        ps.LevelIcon,
+		Endorsement:      ps.Endorsement,
+		EndorsementIcon:  ps.EndorsementIcon,
+		Prestige:         ps.Prestige,
+		PrestigeIcon:     ps.PrestigeIcon,
+		GamesWon:         ps.GamesWon,
+		QuickPlayStats:   quickPlayStatsV1{newStatsCollectionV1(ps.QuickPlayStats.StatsCollection)},
+		CompetitiveStats: competitiveStatsV1{ps.CompetitiveStats.Season, newStatsCollectionV1(ps.CompetitiveStats.StatsCollection)},
+		Private:          ps.Private,
+	}
+	if ps.Ratings != nil {
+		v.Ratings = make([]ratingV1, len(ps.Ratings))
+		for i, r := range ps.Ratings {
+			v.Ratings[i] = ratingV1{Level: r.Level, Role: r.Role, RoleIcon: r.RoleIcon, RankIcon: r.RankIcon}
+		}
+	}
+	return v
+}
+
+// newStatsCollectionV1 converts a game modes stats to the v1 schema, keeping
+// missing maps null as v1 always served them
+func newStatsCollectionV1(sc ovrstat.StatsCollection) statsCollectionV1 {
+	var v statsCollectionV1
+	if sc.TopHeroes != nil {
+		v.TopHeroes = make(map[string]*topHeroV1, len(sc.TopHeroes))
+		for hero, th := range sc.TopHeroes {
+			v.TopHeroes[hero] = &topHeroV1{
+				TimePlayed:          th.TimePlayed,
+				GamesWon:            th.GamesWon,
+				WinPercentage:       th.WinPercentage,
+				WeaponAccuracy:      th.WeaponAccuracy,
+				EliminationsPerLife: th.EliminationsPerLife,
+				MultiKillBest:       th.MultiKillBest,
+				ObjectiveKills:      th.ObjectiveKills,
+			}
+		}
+	}
+	if sc.CareerStats != nil {
+		v.CareerStats = make(map[string]*careerStatsV1, len(sc.CareerStats))
+		for hero, cs := range sc.CareerStats {
+			cs = cs.Legacy()
+			v.CareerStats[hero] = &careerStatsV1{
+				Assists:       cs.Assists,
+				Average:       cs.Average,
+				Best:          cs.Best,
+				Combat:        cs.Combat,
+				Deaths:        cs.Deaths,
+				HeroSpecific:  cs.HeroSpecific,
+				Game:          cs.Game,
+				MatchAwards:   cs.MatchAwards,
+				Miscellaneous: cs.Miscellaneous,
+			}
+		}
+	}
+	return v
+}
+
+// stats handles retrieving and serving Overwatch stats in JSON using the v1
+// schema
+func (h *handler) stats(c echo.Context) error {
+	return h.serveStats(c, func(stats *ovrstat.PlayerStats) interface{} { return newPlayerStatsV1(stats) })
+}
+
+// playerStatsV2 is the v2 schema of a players stats. Unlike v1 its keys are
+// consistently cased, durations are numbers of seconds and career stats are
+// only served as typed values
+type playerStatsV2 struct {
+	ID           int                `json:"id"`
+	Tag          string             `json:"tag"`
+	Name         string             `json:"name"`
+	Icon         string             `json:"icon"`
+	Level        int                `json:"level"`
+	LevelIcon    string             `json:"levelIcon"`
+	Prestige     int                `json:"prestige"`
+	PrestigeIcon string             `json:"prestigeIcon"`
+	Endorsement  endorsementV2      `json:"endorsement"`
+	Ratings      []ovrstat.Rating   `json:"ratings"`
+	GamesWon     int                `json:"gamesWon"`
+	Private      bool               `json:"private"`
+	QuickPlay    modeStatsV2        `json:"quickPlay"`
+	Competitive  competitiveStatsV2 `json:"competitive"`
+}
+
+// endorsementV2 is a players endorsement level and its icon
+type endorsementV2 struct {
+	Level int    `json:"level"`
+	Icon  string `json:"icon"`
+}
+
+// modeStatsV2 holds the stats of a single game mode, keyed by hero
+type modeStatsV2 struct {
+	TopHeroes   map[string]topHeroV2      `json:"topHeroes"`
+	CareerStats map[string][]ovrstat.Stat `json:"careerStats"`
+}
+
+// competitiveStatsV2 holds the competitive stats of the current season
+type competitiveStatsV2 struct {
+	Season *int `json:"season"`
+	modeStatsV2
+}
+
+// topHeroV2 holds the basic stats of a hero
+type topHeroV2 struct {
+	TimePlayed          ovrstat.Duration `json:"timePlayed"`
+	GamesWon            int              `json:"gamesWon"`
+	WinPercentage       int              `json:"winPercentage"`
+	WeaponAccuracy      int              `json:"weaponAccuracy"`
+	EliminationsPerLife float64          `json:"eliminationsPerLife"`
+	MultiKillBest       int              `json:"multiKillBest"`
+	ObjectiveKills      float64          `json:"objectiveKills"`
+}
+
+// newPlayerStatsV2 converts stats to the v2 schema
+func newPlayerStatsV2(ps *ovrstat.PlayerStats) *playerStatsV2 {
+	v := &playerStatsV2{
+		ID:           ps.ID,
+		Tag:          ps.Tag,
+		Name:         ps.Name,
+		Icon:         ps.Icon,
+		Level:        ps.Level,
+		LevelIcon:    ps.LevelIcon,
+		Prestige:     ps.Prestige,
+		PrestigeIcon: ps.PrestigeIcon,
+		Endorsement:  endorsementV2{Level: ps.Endorsement, Icon: ps.EndorsementIcon},
+		Ratings:      ps.Ratings,
+		GamesWon:     ps.GamesWon,
+		Private:      ps.Private,
+		QuickPlay:    newModeStatsV2(ps.QuickPlayStats.StatsCollection),
+		Competitive: competitiveStatsV2{
+			Season:      ps.CompetitiveStats.Season,
+			modeStatsV2: newModeStatsV2(ps.CompetitiveStats.StatsCollection),
+		},
+	}
+	if v.Ratings == nil {
+		v.Ratings = []ovrstat.Rating{}
+	}
+	return v
+}
+
+// newModeStatsV2 converts a game modes stats to the v2 schema
+func newModeStatsV2(sc ovrstat.StatsCollection) modeStatsV2 {
+	m := modeStatsV2{
+		TopHeroes:   make(map[string]topHeroV2, len(sc.TopHeroes)),
+		CareerStats: make(map[string][]ovrstat.Stat, len(sc.CareerStats)),
+	}
+	for hero, th := range sc.TopHeroes {
+		m.TopHeroes[hero] = topHeroV2{
+			TimePlayed:          th.TimePlayedDuration,
+			GamesWon:            th.GamesWon,
+			WinPercentage:       th.WinPercentage,
+			WeaponAccuracy:      th.WeaponAccuracy,
+			EliminationsPerLife: th.EliminationsPerLife,
+			MultiKillBest:       th.MultiKillBest,
+			ObjectiveKills:      th.ObjectiveKills,
+		}
+	}
+	for hero, cs := range sc.CareerStats {
+		stats := cs.Stats
+		if stats == nil {
+			stats = []ovrstat.Stat{}
+		}
+		m.CareerStats[hero] = stats
+	}
+	return m
+}
+
+// statsV2 handles retrieving and serving Overwatch stats in JSON using the v2
+// schema
+func (h *handler) statsV2(c echo.Context) error {
+	return h.serveStats(c, func(stats *ovrstat.PlayerStats) interface{} { return newPlayerStatsV2(stats) })
+}
+
+// deprecateV1 announces the deprecation and planned removal of v1 on every v1
+// response once configured, linking the v2 equivalent of the request
+func (a APIConfig) deprecateV1(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		hdr := c.Response().Header()
+		if !a.V1Deprecation.IsZero() {
+			hdr.Set("Deprecation", "@"+strconv.FormatInt(a.V1Deprecation.Unix(), 10))
+		}
+		if !a.V1Sunset.IsZero() {
+			hdr.Set("Sunset", a.V1Sunset.UTC().Format(http.TimeFormat))
+		}
+		if !a.V1Deprecation.IsZero() || !a.V1Sunset.IsZero() {
+			path := strings.TrimPrefix(c.Request().URL.EscapedPath(), "/v1")
+			hdr.Add("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, "/v2"+path))
+		}
+		return next(c)
+	}
+}
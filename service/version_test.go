@@ -0,0 +1,109 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestDeprecateV1(t *testing.T) {
+	serve := func(cfg APIConfig, path string) http.Header {
+		e := echo.New()
+		e.GET("/v1/stats/:platform/:tag", func(c echo.Context) error {
+			return c.NoContent(http.StatusOK)
+		}, cfg.deprecateV1)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec.Header()
+	}
+
+	// Nothing is announced until v1 is deprecated
+	if hdr := serve(APIConfig{}, "/v1/stats/pc/Viz-1213"); hdr.Get("Deprecation") != "" || hdr.Get("Link") != "" {
+		t.Errorf("Expected no deprecation headers, got %v", hdr)
+	}
+
+	hdr := serve(APIConfig{
+		V1Deprecation: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		V1Sunset:      time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC),
+	}, "/v1/stats/xbl/Lt%20Evolution")
+	if got := hdr.Get("Deprecation"); got != "@1767225600" {
+		t.Errorf("Expected a Deprecation date, got %q", got)
+	}
+	if got := hdr.Get("Sunset"); got != "Wed, 01 Jul 2026 00:00:00 GMT" {
+		t.Errorf("Expected a Sunset date, got %q", got)
+	}
+	if got := hdr.Get("Link"); got != `</v2/stats/xbl/Lt%20Evolution>; rel="successor-version"` {
+		t.Errorf("Expected a link to v2, got %q", got)
+	}
+}
+
+// TestStatsV1Golden pins v1 responses to the JSON served before versioning.
+// The golden files are the output of the original parser and must never be
+// regenerated, a difference means the v1 schema changed
+func TestStatsV1Golden(t *testing.T) {
+	tests := []struct {
+		name, tag, career, search string
+	}{
+		{"pc-public", "Viz-1213", "pc-public.html", "viz.json"},
+		{"pc-private", "Hidden-4321", "pc-private.html", "hidden.json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fixture := filepath.Join("career", tt.career)
+				if strings.HasPrefix(r.URL.Path, "/search/") {
+					fixture = filepath.Join("search", tt.search)
+				}
+				http.ServeFile(w, r, filepath.Join("..", "ovrstat", "testdata", fixture))
+			}))
+			defer srv.Close()
+
+			cfg := DefaultConfig()
+			cfg.Upstream.BaseURL = srv.URL + "/career"
+			cfg.Upstream.APIURL = srv.URL + "/search/"
+			cfg.Log.Level = slog.LevelError
+			e, _ := newService(cfg)
+
+			want, err := os.ReadFile(filepath.Join("testdata", "v1", tt.name+".json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, path := range []string{"/v1/stats/pc/", "/stats/pc/"} {
+				rec := httptest.NewRecorder()
+				e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path+tt.tag, nil))
+				var got bytes.Buffer
+				if err := json.Indent(&got, bytes.TrimSpace(rec.Body.Bytes()), "", "  "); err != nil {
+					t.Fatalf("%s: %d %v", path, rec.Code, err)
+				}
+				got.WriteByte('\n')
+				if rec.Code != http.StatusOK || !bytes.Equal(got.Bytes(), want) {
+					t.Errorf("%s: the v1 response differs from the baseline, got %d:\n%s", path, rec.Code, got.String())
+				}
+			}
+
+			// A batch serves the same stats
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/stats/batch",
+				strings.NewReader(`{"players": [{"platform": "pc", "tag": "`+tt.tag+`"}]}`))
+			req.Header.Set("Content-Type", "application/json")
+			e.ServeHTTP(rec, req)
+			var res batchResponse[json.RawMessage]
+			json.Unmarshal(rec.Body.Bytes(), &res)
+			var got bytes.Buffer
+			if len(res.Results) != 1 || res.Results[0].Stats == nil ||
+				json.Indent(&got, *res.Results[0].Stats, "", "  ") != nil ||
+				!bytes.Equal(append(got.Bytes(), '\n'), want) {
+				t.Errorf("The v1 batch response differs from the baseline, got %s", rec.Body.String())
+			}
+		})
+	}
+}